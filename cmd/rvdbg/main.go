@@ -14,10 +14,12 @@ import (
 	"os"
 
 	cli "github.com/deadsy/go-cli"
+	"github.com/deadsy/rvdbg/cpu/riscv/rv13"
 	"github.com/deadsy/rvdbg/itf"
 	"github.com/deadsy/rvdbg/target"
 	"github.com/deadsy/rvdbg/target/aphx"
 	"github.com/deadsy/rvdbg/target/gd32v"
+	"github.com/deadsy/rvdbg/target/generic"
 	"github.com/deadsy/rvdbg/target/maixgo"
 	"github.com/deadsy/rvdbg/target/redv"
 	"github.com/deadsy/rvdbg/target/wap"
@@ -31,7 +33,7 @@ const MHz = 1000
 
 //-----------------------------------------------------------------------------
 
-func run(info *target.Info) error {
+func run(info *target.Info, auth rv13.Authenticator) error {
 
 	// create the debug interface
 	jtagDriver, err := itf.NewJtagDriver(info.DbgType, info.DbgSpeed)
@@ -48,11 +50,13 @@ func run(info *target.Info) error {
 	case "wap":
 		tgt, err = wap.New(jtagDriver)
 	case "maixgo":
-		tgt, err = maixgo.New(jtagDriver)
+		tgt, err = maixgo.New(jtagDriver, auth)
 	case "gd32v":
-		tgt, err = gd32v.New(jtagDriver)
+		tgt, err = gd32v.New(jtagDriver, auth)
+	case "generic":
+		tgt, err = generic.New(jtagDriver, auth)
 	case "redv":
-		tgt, err = redv.New(jtagDriver)
+		tgt, err = redv.New(jtagDriver, auth)
 	}
 	if err != nil {
 		return err
@@ -82,6 +86,7 @@ func run(info *target.Info) error {
 func addTargets() {
 	target.Add(&aphx.Info)
 	target.Add(&gd32v.Info)
+	target.Add(&generic.Info)
 	target.Add(&maixgo.Info)
 	target.Add(&redv.Info)
 	target.Add(&wap.Info)
@@ -102,6 +107,7 @@ func main() {
 
 	targetName := flag.String("t", "", "target name")
 	interfaceName := flag.String("i", "", "debug interface name")
+	password := flag.String("p", "", "debug module password (comma separated 32-bit hex words)")
 	flag.Parse()
 
 	if *targetName == "" {
@@ -137,7 +143,18 @@ func main() {
 		info.DbgType = x.Type
 	}
 
-	err := run(&info)
+	// authenticator for a locked debug module
+	var auth rv13.Authenticator
+	if *password != "" {
+		pw, err := rv13.ParsePassword(*password)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", err)
+			os.Exit(1)
+		}
+		auth = &rv13.PasswordAuth{Password: pw}
+	}
+
+	err := run(&info, auth)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "%s\n", err)
 		os.Exit(1)
@@ -0,0 +1,246 @@
+//-----------------------------------------------------------------------------
+/*
+
+RISC-V Breakpoint Commands
+
+*/
+//-----------------------------------------------------------------------------
+
+package riscv
+
+import (
+	"fmt"
+	"strconv"
+
+	cli "github.com/deadsy/go-cli"
+	"github.com/deadsy/rvdbg/cpu/riscv/rv"
+	"github.com/deadsy/rvdbg/util"
+)
+
+//-----------------------------------------------------------------------------
+
+// haltedDo runs a function with the current hart halted.
+// If the hart was running (and was halted by us) it is resumed afterwards.
+func haltedDo(dbg rv.Debug, f func() error) error {
+	hi := dbg.GetCurrentHart()
+	wasRunning := hi.State == rv.Running
+	err := dbg.HaltHart()
+	if err != nil {
+		return fmt.Errorf("unable to halt hart%d: %v", hi.ID, err)
+	}
+	ferr := f()
+	if wasRunning {
+		// don't resume a hart that stopped for some other reason
+		cause, err := rv.GetHaltCause(dbg)
+		if err != nil {
+			return err
+		}
+		if cause == rv.CauseHaltReq {
+			err := dbg.ResumeHart()
+			if err != nil {
+				return fmt.Errorf("unable to resume hart%d: %v", hi.ID, err)
+			}
+		}
+	}
+	return ferr
+}
+
+// haltString returns a string describing why the current hart halted.
+// An empty string is returned for a debugger halt request.
+func haltString(dbg rv.Debug, syms *Symbols) (string, error) {
+	hi := dbg.GetCurrentHart()
+	cause, err := rv.GetHaltCause(dbg)
+	if err != nil {
+		return "", err
+	}
+	if cause == rv.CauseHaltReq {
+		return "", nil
+	}
+	pc, err := dbg.RdCSR(rv.DPC, 0)
+	if err != nil {
+		return "", err
+	}
+	pcStr := fmt.Sprintf(util.UintFormat(hi.MXLEN), pc)
+	if sym := syms.AddrString(uint(pc)); sym != "" {
+		pcStr += fmt.Sprintf(" (%s)", sym)
+	}
+	if cause == rv.CauseTrigger || cause == rv.CauseEbreak {
+		bp, err := rv.HitBreakpoint(dbg)
+		if err != nil {
+			return "", err
+		}
+		if bp != nil {
+			return fmt.Sprintf("hart%d halted: breakpoint %d at %s", hi.ID, bp.ID, pcStr), nil
+		}
+	}
+	if cause == rv.CauseTrigger {
+		wp, err := rv.HitWatchpoint(dbg)
+		if err != nil {
+			return "", err
+		}
+		if wp != nil {
+			return fmt.Sprintf("hart%d halted: watchpoint %d (%s) at %s", hi.ID, wp.ID, wp, pcStr), nil
+		}
+	}
+	return fmt.Sprintf("hart%d halted: %s at %s", hi.ID, cause, pcStr), nil
+}
+
+//-----------------------------------------------------------------------------
+
+// idArg converts a breakpoint/watchpoint identifier argument.
+func idArg(args []string) (int, error) {
+	err := cli.CheckArgc(args, []int{1})
+	if err != nil {
+		return 0, err
+	}
+	id, err := strconv.Atoi(args[0])
+	if err != nil {
+		return 0, fmt.Errorf("invalid id \"%s\"", args[0])
+	}
+	return id, nil
+}
+
+// bpIDHelp is help for commands taking a breakpoint identifier.
+var bpIDHelp = []cli.Help{
+	{"<id>", "breakpoint identifier"},
+}
+
+// bpAddHelp is help for the breakpoint add command.
+var bpAddHelp = []cli.Help{
+	{"<addr> [type]", "add a breakpoint"},
+	{"  addr", "address (hex) or symbol name"},
+	{"  type", "hw (trigger) or sw (ebreak), default is hw if a trigger is free"},
+}
+
+var bpType = map[string]rv.BreakType{
+	"hw": rv.BreakHardware,
+	"sw": rv.BreakSoftware,
+}
+
+var cmdBpAdd = cli.Leaf{
+	Descr: "add a breakpoint",
+	F: func(c *cli.CLI, args []string) {
+		dbg := c.User.(target).GetRiscvDebug()
+		err := cli.CheckArgc(args, []int{1, 2})
+		if err != nil {
+			c.User.Put(fmt.Sprintf("%s\n", err))
+			return
+		}
+		typ := rv.BreakAuto
+		if len(args) == 2 {
+			var ok bool
+			typ, ok = bpType[args[1]]
+			if !ok {
+				c.User.Put(fmt.Sprintf("unknown breakpoint type \"%s\"\n", args[1]))
+				return
+			}
+		}
+		var addr uint
+		if s := c.User.(target).GetSymbols().Lookup(args[0]); s != nil {
+			addr = s.Addr
+		} else {
+			maxAddr := uint((1 << dbg.GetAddressSize()) - 1)
+			addr, err = cli.UintArg(args[0], [2]uint{0, maxAddr}, 16)
+			if err != nil {
+				c.User.Put(fmt.Sprintf("%s\n", err))
+				return
+			}
+		}
+		var bp *rv.Breakpoint
+		err = haltedDo(dbg, func() error {
+			var err error
+			bp, err = rv.AddBreakpoint(dbg, addr, typ)
+			return err
+		})
+		if err != nil {
+			c.User.Put(fmt.Sprintf("unable to add breakpoint: %v\n", err))
+			return
+		}
+		c.User.Put(fmt.Sprintf("breakpoint %d (%s) at 0x%x\n", bp.ID, bp.Type, bp.Addr))
+	},
+}
+
+var cmdBpDelete = cli.Leaf{
+	Descr: "delete a breakpoint",
+	F: func(c *cli.CLI, args []string) {
+		dbg := c.User.(target).GetRiscvDebug()
+		id, err := idArg(args)
+		if err != nil {
+			c.User.Put(fmt.Sprintf("%s\n", err))
+			return
+		}
+		err = haltedDo(dbg, func() error {
+			return rv.RemoveBreakpoint(dbg, id)
+		})
+		if err != nil {
+			c.User.Put(fmt.Sprintf("unable to delete breakpoint: %v\n", err))
+		}
+	},
+}
+
+func bpEnable(c *cli.CLI, args []string, enable bool) {
+	dbg := c.User.(target).GetRiscvDebug()
+	id, err := idArg(args)
+	if err != nil {
+		c.User.Put(fmt.Sprintf("%s\n", err))
+		return
+	}
+	err = haltedDo(dbg, func() error {
+		return rv.EnableBreakpoint(dbg, id, enable)
+	})
+	if err != nil {
+		c.User.Put(fmt.Sprintf("unable to %s breakpoint: %v\n", []string{"disable", "enable"}[util.BoolToInt(enable)], err))
+	}
+}
+
+var cmdBpEnable = cli.Leaf{
+	Descr: "enable a breakpoint",
+	F: func(c *cli.CLI, args []string) {
+		bpEnable(c, args, true)
+	},
+}
+
+var cmdBpDisable = cli.Leaf{
+	Descr: "disable a breakpoint",
+	F: func(c *cli.CLI, args []string) {
+		bpEnable(c, args, false)
+	},
+}
+
+var cmdBpList = cli.Leaf{
+	Descr: "list the breakpoints",
+	F: func(c *cli.CLI, args []string) {
+		dbg := c.User.(target).GetRiscvDebug()
+		c.User.Put(fmt.Sprintf("%s\n", rv.BreakpointsString(dbg)))
+	},
+}
+
+var cmdBpTriggers = cli.Leaf{
+	Descr: "display the trigger module",
+	F: func(c *cli.CLI, args []string) {
+		dbg := c.User.(target).GetRiscvDebug()
+		var s string
+		err := haltedDo(dbg, func() error {
+			var err error
+			s, err = rv.TriggersString(dbg)
+			return err
+		})
+		if err != nil {
+			c.User.Put(fmt.Sprintf("unable to read triggers: %v\n", err))
+			return
+		}
+		c.User.Put(fmt.Sprintf("%s\n", s))
+	},
+}
+
+// BpMenu is the breakpoint submenu.
+var BpMenu = cli.Menu{
+	{"add", cmdBpAdd, bpAddHelp},
+	{"delete", cmdBpDelete, bpIDHelp},
+	{"disable", cmdBpDisable, bpIDHelp},
+	{"enable", cmdBpEnable, bpIDHelp},
+	{"list", cmdBpList},
+	{"triggers", cmdBpTriggers},
+}
+
+//-----------------------------------------------------------------------------
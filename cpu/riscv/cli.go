@@ -14,8 +14,11 @@ import (
 	"strings"
 
 	cli "github.com/deadsy/go-cli"
+	"github.com/deadsy/rvda"
+	"github.com/deadsy/rvdbg/cpu/riscv/config"
 	"github.com/deadsy/rvdbg/cpu/riscv/rv"
 	"github.com/deadsy/rvdbg/soc"
+	"github.com/deadsy/rvdbg/util"
 )
 
 //-----------------------------------------------------------------------------
@@ -24,23 +27,99 @@ import (
 type target interface {
 	GetRiscvDebug() rv.Debug
 	GetCSR() (*soc.Device, soc.Driver)
+	GetSoC() (*soc.Device, soc.Driver)
+	GetSymbols() *Symbols
 }
 
 //-----------------------------------------------------------------------------
-// display CSR
+// display/write CSR
 
 // CsrHelp is help information for the "csr" command.
 var CsrHelp = []cli.Help{
-	{"[register]", "register (string) - register name (or *)"},
 	{"<cr>", "display all registers"},
+	{"*", "display all registers and fields"},
+	{"<reg>", "display a register"},
+	{"<reg> <value>", "write a register"},
+	{"<reg>.<field> <value>", "write a register field"},
+	{"<reg>.<field>=<value>", "write a register field"},
+	{"  reg", "register name (mstatus) or number (0x300)"},
+	{"  field", "field name (mie)"},
+	{"  value", "value (hex) or field enumeration name"},
 }
 
-// CmdCSR displays the control and status registers.
+// lookupCSR returns a CSR register by name or number.
+func lookupCSR(p *soc.Peripheral, name string) (*soc.Register, error) {
+	r, err := p.GetRegister(name)
+	if err == nil {
+		return r, nil
+	}
+	if strings.HasPrefix(name, "0x") {
+		n, err := cli.UintArg(name, [2]uint{0, 0xfff}, 16)
+		if err != nil {
+			return nil, err
+		}
+		for i := range p.Registers {
+			if p.Registers[i].Offset == n {
+				return &p.Registers[i], nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("no register \"%s\" (run \"csr\" for the names)", name)
+}
+
+// fieldValue returns the value for a register field from a hex value or enumeration name.
+func fieldValue(f *soc.Field, arg string) (uint, error) {
+	for k, v := range f.Enums {
+		if v == arg {
+			return k, nil
+		}
+	}
+	return cli.UintArg(arg, [2]uint{0, util.Mask(f.Msb-f.Lsb, 0)}, 16)
+}
+
+// wrCSR writes a CSR (or a field of a CSR) from command arguments.
+func wrCSR(dbg rv.Debug, p *soc.Peripheral, name, arg string) error {
+	rname, fname := name, ""
+	if i := strings.Index(name, "."); i >= 0 {
+		rname, fname = name[:i], name[i+1:]
+	}
+	r, err := lookupCSR(p, rname)
+	if err != nil {
+		return err
+	}
+	size := rv.GetCSRSize(r.Offset, dbg.GetCurrentHart())
+	if fname == "" {
+		// write the whole register
+		val, err := cli.UintArg(arg, [2]uint{0, util.Mask(size-1, 0)}, 16)
+		if err != nil {
+			return err
+		}
+		return dbg.WrCSR(r.Offset, 0, uint64(val))
+	}
+	// read/modify/write a register field
+	f := r.GetField(fname)
+	if f == nil {
+		return fmt.Errorf("no field \"%s\" in register \"%s\" (run \"csr %s\" for the names)", fname, r.Name, r.Name)
+	}
+	val, err := fieldValue(f, arg)
+	if err != nil {
+		return err
+	}
+	x, err := dbg.RdCSR(r.Offset, 0)
+	if err != nil {
+		return err
+	}
+	mask := uint64(util.Mask(f.Msb, f.Lsb))
+	x = (x &^ mask) | ((uint64(val) << f.Lsb) & mask)
+	return dbg.WrCSR(r.Offset, 0, x)
+}
+
+// CmdCSR displays and writes the control and status registers.
 var CmdCSR = cli.Leaf{
-	Descr: "display control and status registers",
+	Descr: "display/write control and status registers",
 	F: func(c *cli.CLI, args []string) {
 
-		err := cli.CheckArgc(args, []int{0, 1})
+		err := cli.CheckArgc(args, []int{0, 1, 2})
 		if err != nil {
 			c.User.Put(fmt.Sprintf("%s\n", err))
 			return
@@ -60,9 +139,25 @@ var CmdCSR = cli.Leaf{
 			return
 		}
 
-		r, err := p.GetRegister(args[0])
+		// <reg>.<field>=<value>
+		if i := strings.Index(args[0], "="); len(args) == 1 && i >= 0 {
+			args = []string{args[0][:i], args[0][i+1:]}
+		}
+
+		name := strings.ToLower(args[0])
+
+		if len(args) == 2 {
+			dbg := c.User.(target).GetRiscvDebug()
+			err := wrCSR(dbg, p, name, args[1])
+			if err != nil {
+				c.User.Put(fmt.Sprintf("%s\n", err))
+			}
+			return
+		}
+
+		r, err := lookupCSR(p, name)
 		if err != nil {
-			c.User.Put(fmt.Sprintf("no register \"%s\" (run \"csr\" for the names)\n", args[0]))
+			c.User.Put(fmt.Sprintf("%s\n", err))
 			return
 		}
 
@@ -111,17 +206,76 @@ func gprString(reg []uint64, xlen uint) string {
 	return strings.Join(s, "\n")
 }
 
-// CmdGpr displays the general purpose registers.
+// gprNumber returns the register number for an ABI (a0) or numeric (x10) GPR name.
+func gprNumber(name string, nregs int) (uint, error) {
+	for i := 0; i < nregs; i++ {
+		if name == abiXName[i] || name == fmt.Sprintf("x%d", i) {
+			return uint(i), nil
+		}
+	}
+	if name == "fp" {
+		// s0 is also the frame pointer
+		return rv.RegS0, nil
+	}
+	return 0, fmt.Errorf("no register \"%s\"", name)
+}
+
+// GprHelp is help information for the "gpr" command.
+var GprHelp = []cli.Help{
+	{"<cr>", "display all registers"},
+	{"<reg>", "display a register"},
+	{"<reg> <value>", "write a register"},
+	{"  reg", "register name (a0, x10, pc)"},
+	{"  value", "value (hex)"},
+}
+
+// CmdGpr displays and writes the general purpose registers.
 var CmdGpr = cli.Leaf{
-	Descr: "display general purpose registers",
+	Descr: "display/write general purpose registers",
 	F: func(c *cli.CLI, args []string) {
+		err := cli.CheckArgc(args, []int{0, 1, 2})
+		if err != nil {
+			c.User.Put(fmt.Sprintf("%s\n", err))
+			return
+		}
 		dbg := c.User.(target).GetRiscvDebug()
 		hi := dbg.GetCurrentHart()
-		err := dbg.HaltHart()
+		err = dbg.HaltHart()
 		if err != nil {
 			c.User.Put(fmt.Sprintf("unable to halt hart%d: %v\n", hi.ID, err))
 			return
 		}
+		if len(args) != 0 {
+			name := strings.ToLower(args[0])
+			if name == "pc" {
+				pcCommand(c, dbg, args[1:])
+				return
+			}
+			reg, err := gprNumber(name, hi.Nregs)
+			if err != nil {
+				c.User.Put(fmt.Sprintf("%s\n", err))
+				return
+			}
+			if len(args) == 2 {
+				val, err := cli.UintArg(args[1], [2]uint{0, util.Mask(hi.MXLEN-1, 0)}, 16)
+				if err != nil {
+					c.User.Put(fmt.Sprintf("%s\n", err))
+					return
+				}
+				err = dbg.WrGPR(reg, 0, uint64(val))
+				if err != nil {
+					c.User.Put(fmt.Sprintf("unable to write %s: %v\n", name, err))
+				}
+				return
+			}
+			x, err := dbg.RdGPR(reg, 0)
+			if err != nil {
+				c.User.Put(fmt.Sprintf("unable to read %s: %v\n", name, err))
+				return
+			}
+			c.User.Put(fmt.Sprintf("x%d %s "+util.UintFormat(hi.MXLEN)+"\n", reg, abiXName[reg], x))
+			return
+		}
 		// slice of register values, +1 for the pc
 		reg := make([]uint64, hi.Nregs+1)
 		// read the GPRs
@@ -145,99 +299,192 @@ var CmdGpr = cli.Leaf{
 }
 
 //-----------------------------------------------------------------------------
-// display floating point register set
+// display/write the pc
 
-var abiFName = [32]string{
-	"ft0", "ft1", "ft2", "ft3", "ft4", "ft5", "ft6", "ft7",
-	"fs0", "fs1", "fa0", "fa1", "fa2", "fa3", "fa4", "fa5",
-	"fa6", "fa7", "fs2", "fs3", "fs4", "fs5", "fs6", "fs7",
-	"fs8", "fs9", "fs10", "fs11", "ft8", "ft9", "ft10", "ft11",
+// PCHelp is help information for the "pc" command.
+var PCHelp = []cli.Help{
+	{"<cr>", "display the pc"},
+	{"<addr>", "set the pc (hex)"},
 }
 
-var fprCache []uint64
-
-func fprString(reg []uint64, flen uint) string {
-	fmtx := "%08x"
-	if flen == 64 {
-		fmtx = "%016x"
-	}
-	if fprCache == nil {
-		fprCache = reg
-	}
-	s := make([]string, len(reg))
-	for i := 0; i < len(reg); i++ {
-		delta := ""
-		if reg[i] != fprCache[i] {
-			delta = " *"
+// pcCommand displays or writes the pc of the current (halted) hart.
+func pcCommand(c *cli.CLI, dbg rv.Debug, args []string) {
+	hi := dbg.GetCurrentHart()
+	if len(args) == 1 {
+		pc, err := cli.UintArg(args[0], [2]uint{0, util.Mask(hi.DXLEN-1, 0)}, 16)
+		if err != nil {
+			c.User.Put(fmt.Sprintf("%s\n", err))
+			return
 		}
-		regStr := fmt.Sprintf("f%d", i)
-		valStr := "0"
-		if reg[i] != 0 {
-			valStr = fmt.Sprintf(fmtx, reg[i])
+		// the hart resumes at dpc
+		err = dbg.WrCSR(rv.DPC, 0, uint64(pc))
+		if err != nil {
+			c.User.Put(fmt.Sprintf("unable to write pc: %v\n", err))
 		}
-		s[i] = fmt.Sprintf("%-4s %-4s %s%s", regStr, abiFName[i], valStr, delta)
+		return
 	}
-	fprCache = reg
-	return strings.Join(s, "\n")
+	s, err := pcString(dbg, c.User.(target).GetSymbols())
+	if err != nil {
+		c.User.Put(fmt.Sprintf("%s\n", err))
+		return
+	}
+	c.User.Put(fmt.Sprintf("%s\n", s))
 }
 
-// CmdFpr displays the floating point registers.
-var CmdFpr = cli.Leaf{
-	Descr: "display floating point registers",
+// CmdPC displays and writes the pc.
+var CmdPC = cli.Leaf{
+	Descr: "display/write the program counter",
 	F: func(c *cli.CLI, args []string) {
+		err := cli.CheckArgc(args, []int{0, 1})
+		if err != nil {
+			c.User.Put(fmt.Sprintf("%s\n", err))
+			return
+		}
 		dbg := c.User.(target).GetRiscvDebug()
 		hi := dbg.GetCurrentHart()
-		err := dbg.HaltHart()
+		err = dbg.HaltHart()
 		if err != nil {
 			c.User.Put(fmt.Sprintf("unable to halt hart%d: %v\n", hi.ID, err))
 			return
 		}
-		// slice of register values
-		reg := make([]uint64, hi.Nregs)
-		// read the FPRs
-		for i := 0; i < hi.Nregs; i++ {
-			var err error
-			reg[i], err = dbg.RdFPR(uint(i), 0)
-			if err != nil {
-				c.User.Put(fmt.Sprintf("unable to read fpr%d: %v\n", i, err))
-				return
-			}
-		}
-		c.User.Put(fmt.Sprintf("%s\n", fprString(reg, hi.FLEN)))
+		pcCommand(c, dbg, args)
 	},
 }
 
 //-----------------------------------------------------------------------------
 
+// allArg returns true if the command argument is "all".
+func allArg(args []string) (bool, error) {
+	err := cli.CheckArgc(args, []int{0, 1})
+	if err != nil {
+		return false, err
+	}
+	if len(args) == 0 {
+		return false, nil
+	}
+	if args[0] != "all" {
+		return false, fmt.Errorf("unknown argument \"%s\"", args[0])
+	}
+	return true, nil
+}
+
+// HaltHelp is help for the halt command.
+var HaltHelp = []cli.Help{
+	{"<cr>", "halt the current hart"},
+	{"all", "halt all harts"},
+}
+
 // CmdHalt halts the current hart.
 var CmdHalt = cli.Leaf{
 	Descr: "halt the current hart",
 	F: func(c *cli.CLI, args []string) {
 		dbg := c.User.(target).GetRiscvDebug()
+		all, err := allArg(args)
+		if err != nil {
+			c.User.Put(fmt.Sprintf("%s\n", err))
+			return
+		}
+		if all {
+			together, err := dbg.HaltAll()
+			if err != nil {
+				c.User.Put(fmt.Sprintf("unable to halt all harts: %v\n", err))
+				return
+			}
+			if !together {
+				c.User.Put("harts were halted one at a time, not simultaneously\n")
+			}
+			s, err := hartTable(dbg)
+			if err != nil {
+				c.User.Put(fmt.Sprintf("%s\n", err))
+				return
+			}
+			c.User.Put(fmt.Sprintf("%s\n", s))
+			return
+		}
 		hi := dbg.GetCurrentHart()
 		if hi.State == rv.Halted {
 			c.User.Put(fmt.Sprintf("hart%d already halted\n", hi.ID))
 			return
 		}
-		err := dbg.HaltHart()
+		err = dbg.HaltHart()
 		if err != nil {
 			c.User.Put(fmt.Sprintf("unable to halt hart%d: %v\n", hi.ID, err))
 			return
 		}
+		// report halts that weren't caused by the halt request
+		s, err := haltString(dbg, c.User.(target).GetSymbols())
+		if err != nil {
+			c.User.Put(fmt.Sprintf("unable to read halt cause: %v\n", err))
+			return
+		}
+		if s != "" {
+			c.User.Put(fmt.Sprintf("%s\n", s))
+		}
 	},
 }
 
+// resumeAll steps each halted hart over any breakpoint and then resumes all harts.
+// It returns true if the harts were resumed together.
+func resumeAll(dbg rv.Debug) (bool, error) {
+	id := dbg.GetCurrentHart().ID
+	for i := 0; i < dbg.GetHartCount(); i++ {
+		hi, err := dbg.SetCurrentHart(i)
+		if err != nil {
+			return false, err
+		}
+		if hi.State != rv.Halted {
+			continue
+		}
+		err = rv.StepOverBreakpoint(dbg)
+		if err != nil {
+			return false, fmt.Errorf("hart%d: %v", i, err)
+		}
+	}
+	_, err := dbg.SetCurrentHart(id)
+	if err != nil {
+		return false, err
+	}
+	return dbg.ResumeAll()
+}
+
+// ResumeHelp is help for the resume command.
+var ResumeHelp = []cli.Help{
+	{"<cr>", "resume the current hart"},
+	{"all", "resume all harts"},
+}
+
 // CmdResume resumes the current hart.
 var CmdResume = cli.Leaf{
 	Descr: "resume the current hart",
 	F: func(c *cli.CLI, args []string) {
 		dbg := c.User.(target).GetRiscvDebug()
+		all, err := allArg(args)
+		if err != nil {
+			c.User.Put(fmt.Sprintf("%s\n", err))
+			return
+		}
+		if all {
+			together, err := resumeAll(dbg)
+			if err != nil {
+				c.User.Put(fmt.Sprintf("unable to resume all harts: %v\n", err))
+				return
+			}
+			if !together {
+				c.User.Put("harts were resumed one at a time, not simultaneously\n")
+			}
+			return
+		}
 		hi := dbg.GetCurrentHart()
 		if hi.State == rv.Running {
 			c.User.Put(fmt.Sprintf("hart%d already running\n", hi.ID))
 			return
 		}
-		err := dbg.ResumeHart()
+		err = rv.StepOverBreakpoint(dbg)
+		if err != nil {
+			c.User.Put(fmt.Sprintf("hart%d: %v\n", hi.ID, err))
+			return
+		}
+		err = dbg.ResumeHart()
 		if err != nil {
 			c.User.Put(fmt.Sprintf("unable to resume hart%d: %v\n", hi.ID, err))
 			return
@@ -247,22 +494,85 @@ var CmdResume = cli.Leaf{
 
 //-----------------------------------------------------------------------------
 
+// privName is the name of the privilege mode in dcsr.prv.
+var privName = []string{"user", "supervisor", "reserved", "machine"}
+
+// hartTable returns a table of state, pc and privilege mode for all harts.
+func hartTable(dbg rv.Debug) (string, error) {
+	id := dbg.GetCurrentHart().ID
+	s := [][]string{}
+	for i := 0; i < dbg.GetHartCount(); i++ {
+		_, err := dbg.SetCurrentHart(i)
+		if err != nil {
+			return "", err
+		}
+		state, err := dbg.GetHartState()
+		if err != nil {
+			return "", err
+		}
+		pc, prv := "-", "-"
+		if state == rv.Halted {
+			x, err := dbg.RdCSR(rv.DPC, 0)
+			if err != nil {
+				return "", err
+			}
+			pc = fmt.Sprintf("%x", x)
+			x, err = dbg.RdCSR(rv.DCSR, 0)
+			if err != nil {
+				return "", err
+			}
+			prv = privName[util.Bits(uint(x), 1, 0)]
+		}
+		mark := []string{"", "*"}[util.BoolToInt(i == id)]
+		s = append(s, []string{fmt.Sprintf("hart%d%s", i, mark), state.String(), pc, prv})
+	}
+	_, err := dbg.SetCurrentHart(id)
+	if err != nil {
+		return "", err
+	}
+	return cli.TableString(s, []int{0, 0, 0, 0}, 1), nil
+}
+
 // HartHelp is help for the hart command.
 var HartHelp = []cli.Help{
 	{"<cr>", "display info for current hart"},
 	{"<id>", "select hart<id> as the current hart"},
+	{"list", "display state, pc and privilege mode for all harts"},
 }
 
+// CmdHart displays hart information and selects the current hart.
 var CmdHart = cli.Leaf{
 	Descr: "hart info/select",
 	F: func(c *cli.CLI, args []string) {
 		dbg := c.User.(target).GetRiscvDebug()
-		hi := dbg.GetCurrentHart()
+		err := cli.CheckArgc(args, []int{0, 1})
+		if err != nil {
+			c.User.Put(fmt.Sprintf("%s\n", err))
+			return
+		}
 		if len(args) == 0 {
-			c.User.Put(fmt.Sprintf("%s\n", hi))
+			c.User.Put(fmt.Sprintf("%s\n", dbg.GetCurrentHart()))
+			return
+		}
+		if args[0] == "list" {
+			s, err := hartTable(dbg)
+			if err != nil {
+				c.User.Put(fmt.Sprintf("%s\n", err))
+				return
+			}
+			c.User.Put(fmt.Sprintf("%s\n", s))
+			return
+		}
+		id, err := cli.UintArg(args[0], [2]uint{0, uint(dbg.GetHartCount() - 1)}, 10)
+		if err != nil {
+			c.User.Put(fmt.Sprintf("%s\n", err))
+			return
+		}
+		_, err = dbg.SetCurrentHart(int(id))
+		if err != nil {
+			c.User.Put(fmt.Sprintf("%s\n", err))
 			return
 		}
-		// TODO
 	},
 }
 
@@ -271,14 +581,14 @@ var CmdHart = cli.Leaf{
 var DisassembleHelp = []cli.Help{
 	{"<addr/name> [len]", "memory region"},
 	{"  addr", "address (hex), default is current pc"},
-	{"  name", "symbol name (string), see \"symbol\" command"},
-	{"  len", "length (hex), defaults to 0x100"},
+	{"  name", "symbol name (string), see \"symbols\" command"},
+	{"  len", "length (hex), defaults to 0x100 or the symbol size"},
 }
 
 const defSize = 0x80
 
 // disassembleArg converts disassemble arguments to an (address, n) tuple.
-func disassembleArg(dbg rv.Debug, args []string) (uint, int, error) {
+func disassembleArg(dbg rv.Debug, syms *Symbols, args []string) (uint, int, error) {
 
 	err := cli.CheckArgc(args, []int{0, 1, 2})
 	if err != nil {
@@ -295,10 +605,19 @@ func disassembleArg(dbg rv.Debug, args []string) (uint, int, error) {
 	}
 
 	// get the address
-	maxAddr := uint((1 << dbg.GetAddressSize()) - 1)
-	addr, err := cli.UintArg(args[0], [2]uint{0, maxAddr}, 16)
-	if err != nil {
-		return 0, 0, err
+	n := defSize
+	var addr uint
+	if s := syms.Lookup(args[0]); s != nil {
+		addr = s.Addr
+		if s.Size != 0 {
+			n = int(s.Size) - 1
+		}
+	} else {
+		maxAddr := uint((1 << dbg.GetAddressSize()) - 1)
+		addr, err = cli.UintArg(args[0], [2]uint{0, maxAddr}, 16)
+		if err != nil {
+			return 0, 0, err
+		}
 	}
 
 	// check address alignment
@@ -316,16 +635,30 @@ func disassembleArg(dbg rv.Debug, args []string) (uint, int, error) {
 	}
 
 	if len(args) == 1 {
-		return addr, defSize, nil
+		return addr, n, nil
 	}
 
 	// get the size
-	n, err := cli.UintArg(args[1], [2]uint{1, 0x100000000}, 16)
+	size, err := cli.UintArg(args[1], [2]uint{1, 0x100000000}, 16)
 	if err != nil {
 		return 0, 0, err
 	}
 
-	return addr, int(n), nil
+	return addr, int(size), nil
+}
+
+// disassemble returns the disassembly of the instruction at an address.
+func disassemble(dbg rv.Debug, syms *Symbols, addr uint) (*rvda.Disassembly, error) {
+	// For a compressed instruction stream we may be reading 32-bit
+	// values with 16-bit alignment. Some chips don't allow this for
+	// data read access, so we always read 2 x 16-bit values.
+	ins, err := dbg.RdMem(16, addr, 2)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read memory at %x", addr)
+	}
+	da := dbg.GetCurrentHart().ISA.Disassemble(addr, (ins[1]<<16)|ins[0])
+	da.Symbol = syms.AddrString(addr)
+	return da, nil
 }
 
 // CmdDisassemble disassembles a region of memory.
@@ -333,24 +666,20 @@ var CmdDisassemble = cli.Leaf{
 	Descr: "disassemble memory",
 	F: func(c *cli.CLI, args []string) {
 		dbg := c.User.(target).GetRiscvDebug()
-		hi := dbg.GetCurrentHart()
+		syms := c.User.(target).GetSymbols()
 		// get the arguments
-		addr, n, err := disassembleArg(dbg, args)
+		addr, n, err := disassembleArg(dbg, syms, args)
 		if err != nil {
 			c.User.Put(fmt.Sprintf("%s\n", err))
 			return
 		}
 		// disassemble
 		for n >= 0 {
-			// For a compressed instruction stream we may be reading 32-bit
-			// values with 16-bit alignment. Some chips don't allow this for
-			// data read access, so we always read 2 x 16-bit values.
-			ins, err := dbg.RdMem(16, addr, 2)
+			da, err := disassemble(dbg, syms, addr)
 			if err != nil {
-				c.User.Put(fmt.Sprintf("unable to read memory at %x\n", addr))
+				c.User.Put(fmt.Sprintf("%s\n", err))
 				return
 			}
-			da := hi.ISA.Disassemble(addr, (ins[1]<<16)|ins[0])
 			c.User.Put(fmt.Sprintf("%s\n", da))
 			addr += da.InsLength
 			n -= int(da.InsLength)
@@ -358,6 +687,23 @@ var CmdDisassemble = cli.Leaf{
 	},
 }
 
+//-----------------------------------------------------------------------------
+// platform configuration
+
+// CmdConfig displays the platform configuration string/dtb.
+var CmdConfig = cli.Leaf{
+	Descr: "display the platform configuration",
+	F: func(c *cli.CLI, args []string) {
+		dbg := c.User.(target).GetRiscvDebug()
+		cfg, err := config.Read(dbg)
+		if err != nil {
+			c.User.Put(fmt.Sprintf("unable to read configuration: %v\n", err))
+			return
+		}
+		c.User.Put(fmt.Sprintf("%s\n", cfg))
+	},
+}
+
 //-----------------------------------------------------------------------------
 
 var cmdRiscvTest1 = cli.Leaf{
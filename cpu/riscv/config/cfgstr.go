@@ -0,0 +1,157 @@
+//-----------------------------------------------------------------------------
+/*
+
+Config String Parsing
+
+Older (privileged spec 1.9) platforms describe themselves with a config string.
+
+platform {
+  vendor ucb;
+  arch spike;
+};
+ram {
+  0 {
+    addr 0x80000000;
+    size 0x10000000;
+  };
+};
+core {
+  0 {
+    0 {
+      isa rv64imafdc;
+    };
+  };
+};
+
+*/
+//-----------------------------------------------------------------------------
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+//-----------------------------------------------------------------------------
+
+// csNode is a config string node.
+type csNode struct {
+	name     string
+	value    map[string]string
+	children []*csNode
+}
+
+// tokenize splits a config string into words, braces and semicolons.
+func tokenize(s string) []string {
+	tokens := []string{}
+	word := []rune{}
+	flush := func() {
+		if len(word) != 0 {
+			tokens = append(tokens, string(word))
+			word = word[:0]
+		}
+	}
+	for _, c := range s {
+		switch {
+		case unicode.IsSpace(c):
+			flush()
+		case c == '{' || c == '}' || c == ';':
+			flush()
+			tokens = append(tokens, string(c))
+		default:
+			word = append(word, c)
+		}
+	}
+	flush()
+	return tokens
+}
+
+// csParse parses the tokens of a node body up to the closing brace (or the end).
+func csParse(n *csNode, tokens []string, top bool) ([]string, error) {
+	for len(tokens) != 0 {
+		t := tokens[0]
+		if t == "}" {
+			if top {
+				return nil, errors.New("config string has unbalanced braces")
+			}
+			return tokens[1:], nil
+		}
+		if t == "{" || t == ";" {
+			tokens = tokens[1:]
+			continue
+		}
+		// find the end of the statement
+		i := 1
+		for i < len(tokens) && tokens[i] != "{" && tokens[i] != ";" && tokens[i] != "}" {
+			i++
+		}
+		if i < len(tokens) && tokens[i] == "{" {
+			if i != 1 {
+				return nil, fmt.Errorf("config string has a bad node name near \"%s\"", t)
+			}
+			child := &csNode{
+				name:  t,
+				value: make(map[string]string),
+			}
+			var err error
+			tokens, err = csParse(child, tokens[2:], false)
+			if err != nil {
+				return nil, err
+			}
+			n.children = append(n.children, child)
+			continue
+		}
+		n.value[t] = strings.Join(tokens[1:i], " ")
+		tokens = tokens[i:]
+	}
+	if !top {
+		return nil, errors.New("config string has unbalanced braces")
+	}
+	return nil, nil
+}
+
+// csUint parses a config string integer value.
+func csUint(s string) (uint, bool) {
+	x, err := strconv.ParseUint(s, 0, 64)
+	return uint(x), err == nil
+}
+
+//-----------------------------------------------------------------------------
+
+// parseConfigString parses a config string.
+func parseConfigString(s string) (*Config, error) {
+	root := &csNode{value: make(map[string]string)}
+	_, err := csParse(root, tokenize(s), true)
+	if err != nil {
+		return nil, err
+	}
+	if len(root.children) == 0 {
+		return nil, errors.New("config string is empty")
+	}
+	cfg := &Config{Format: "config string"}
+	var walk func(n *csNode, path []string)
+	walk = func(n *csNode, path []string) {
+		if len(path) != 0 && path[0] == "platform" {
+			cfg.Model = strings.TrimSpace(n.value["vendor"] + " " + n.value["arch"])
+		}
+		if isa, ok := n.value["isa"]; ok && len(path) != 0 && path[0] == "core" {
+			cfg.Harts = append(cfg.Harts, Hart{ID: uint(len(cfg.Harts)), ISA: isa})
+		}
+		addr, ok0 := csUint(n.value["addr"])
+		size, ok1 := csUint(n.value["size"])
+		if ok0 && ok1 && len(path) != 0 {
+			cfg.addRegion(Region{Name: strings.Join(path, ""), Addr: addr, Size: size, Descr: path[0]})
+		}
+		for _, c := range n.children {
+			walk(c, append(path, c.name))
+		}
+	}
+	walk(root, nil)
+	return cfg, nil
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,162 @@
+//-----------------------------------------------------------------------------
+/*
+
+RISC-V Platform Configuration
+
+The debug module can point to a configuration structure in target memory.
+This is a device tree blob (or a config string on older parts). It
+describes the harts and the memory map of the platform.
+
+*/
+//-----------------------------------------------------------------------------
+
+package config
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	cli "github.com/deadsy/go-cli"
+	"github.com/deadsy/rvdbg/cpu/riscv/rv"
+	"github.com/deadsy/rvdbg/soc"
+	"github.com/deadsy/rvdbg/util"
+)
+
+//-----------------------------------------------------------------------------
+
+// Hart is a hart described by the configuration.
+type Hart struct {
+	ID  uint   // hart id
+	ISA string // isa string
+}
+
+// Region is a memory region described by the configuration.
+type Region struct {
+	Name  string // region name
+	Addr  uint   // base address
+	Size  uint   // size in bytes
+	Descr string // description
+}
+
+// Config is the platform configuration.
+type Config struct {
+	Format  string   // dtb/config string
+	Model   string   // platform model
+	Harts   []Hart   // harts
+	Regions []Region // memory regions
+}
+
+func (cfg *Config) String() string {
+	s := [][]string{}
+	s = append(s, []string{"format", cfg.Format})
+	s = append(s, []string{"model", cfg.Model})
+	for _, h := range cfg.Harts {
+		s = append(s, []string{fmt.Sprintf("hart%d", h.ID), h.ISA})
+	}
+	for _, r := range cfg.Regions {
+		region := fmt.Sprintf("0x%x %s", r.Addr, util.MemSize(r.Size))
+		s = append(s, []string{r.Name, region, r.Descr})
+	}
+	return cli.TableString(s, []int{0, 0, 0}, 1)
+}
+
+// addRegion adds a memory region to the configuration, making the name unique.
+func (cfg *Config) addRegion(r Region) {
+	names := map[string]bool{}
+	for _, x := range cfg.Regions {
+		names[x.Name] = true
+	}
+	if names[r.Name] {
+		base := r.Name
+		for i := 1; names[r.Name]; i++ {
+			r.Name = fmt.Sprintf("%s%d", base, i)
+		}
+	}
+	cfg.Regions = append(cfg.Regions, r)
+}
+
+// NewSoC returns an SoC device built from the configuration memory map.
+func (cfg *Config) NewSoC() *soc.Device {
+	dev := &soc.Device{
+		Name:  cfg.Model,
+		Descr: fmt.Sprintf("from %s", cfg.Format),
+	}
+	for _, r := range cfg.Regions {
+		dev.Peripherals = append(dev.Peripherals, soc.Peripheral{
+			Name:  r.Name,
+			Addr:  r.Addr,
+			Size:  r.Size,
+			Descr: r.Descr,
+		})
+	}
+	return dev
+}
+
+// sort sorts the harts by id and the regions by address.
+func (cfg *Config) sort() {
+	sort.Slice(cfg.Harts, func(i, j int) bool { return cfg.Harts[i].ID < cfg.Harts[j].ID })
+	sort.Slice(cfg.Regions, func(i, j int) bool { return cfg.Regions[i].Addr < cfg.Regions[j].Addr })
+}
+
+//-----------------------------------------------------------------------------
+
+// Parse parses a device tree blob or a config string.
+func Parse(buf []byte) (*Config, error) {
+	var cfg *Config
+	var err error
+	if len(buf) >= 4 && binary.BigEndian.Uint32(buf) == fdtMagic {
+		cfg, err = parseFDT(buf)
+	} else {
+		cfg, err = parseConfigString(string(buf))
+	}
+	if err != nil {
+		return nil, err
+	}
+	cfg.sort()
+	return cfg, nil
+}
+
+//-----------------------------------------------------------------------------
+
+const maxConfigSize = 64 << 10
+const readSize = 256
+
+// Read reads and parses the configuration from target memory.
+func Read(dbg rv.Debug) (*Config, error) {
+	addr, err := dbg.GetConfigAddress()
+	if err != nil {
+		return nil, err
+	}
+	if addr&3 != 0 {
+		return nil, fmt.Errorf("config address 0x%x is not 32-bit aligned", addr)
+	}
+	buf := []byte{}
+	for len(buf) < maxConfigSize {
+		x, err := dbg.RdMem(32, addr+uint(len(buf)), readSize>>2)
+		if err != nil {
+			return nil, err
+		}
+		buf = append(buf, util.ConvertToUint8(32, x)...)
+		if binary.BigEndian.Uint32(buf) == fdtMagic {
+			// the dtb header has the total size
+			size := uint(binary.BigEndian.Uint32(buf[4:]))
+			if size > maxConfigSize {
+				return nil, fmt.Errorf("dtb size %d is too large", size)
+			}
+			if uint(len(buf)) >= size {
+				return Parse(buf[:size])
+			}
+			continue
+		}
+		// the config string is nul terminated
+		if i := strings.IndexByte(string(buf), 0); i >= 0 {
+			return Parse(buf[:i])
+		}
+	}
+	return nil, errors.New("config is too large")
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,279 @@
+//-----------------------------------------------------------------------------
+/*
+
+Platform configuration test functions.
+
+*/
+//-----------------------------------------------------------------------------
+
+package config
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+//-----------------------------------------------------------------------------
+// device tree blob builder
+
+type dtb struct {
+	st     bytes.Buffer      // structure block
+	str    bytes.Buffer      // strings block
+	strOfs map[string]uint32 // strings block offsets
+}
+
+func newDTB() *dtb {
+	return &dtb{strOfs: make(map[string]uint32)}
+}
+
+func (d *dtb) u32(x uint32) {
+	binary.Write(&d.st, binary.BigEndian, x)
+}
+
+func (d *dtb) pad() {
+	for d.st.Len()&3 != 0 {
+		d.st.WriteByte(0)
+	}
+}
+
+func (d *dtb) begin(name string) *dtb {
+	d.u32(fdtBeginNode)
+	d.st.WriteString(name + "\x00")
+	d.pad()
+	return d
+}
+
+func (d *dtb) end() *dtb {
+	d.u32(fdtEndNode)
+	return d
+}
+
+func (d *dtb) prop(name string, val []byte) *dtb {
+	ofs, ok := d.strOfs[name]
+	if !ok {
+		ofs = uint32(d.str.Len())
+		d.strOfs[name] = ofs
+		d.str.WriteString(name + "\x00")
+	}
+	d.u32(fdtProp)
+	d.u32(uint32(len(val)))
+	d.u32(ofs)
+	d.st.Write(val)
+	d.pad()
+	return d
+}
+
+func (d *dtb) blob() []byte {
+	d.u32(fdtEnd)
+	hdr := []uint32{
+		fdtMagic,
+		uint32(40 + d.st.Len() + d.str.Len()), // totalsize
+		40,                                    // off_dt_struct
+		uint32(40 + d.st.Len()),               // off_dt_strings
+		0,                                     // off_mem_rsvmap
+		17,                                    // version
+		16,                                    // last_comp_version
+		0,                                     // boot_cpuid_phys
+		uint32(d.str.Len()),                   // size_dt_strings
+		uint32(d.st.Len()),                    // size_dt_struct
+	}
+	buf := &bytes.Buffer{}
+	binary.Write(buf, binary.BigEndian, hdr)
+	buf.Write(d.st.Bytes())
+	buf.Write(d.str.Bytes())
+	return buf.Bytes()
+}
+
+func cells(x ...uint32) []byte {
+	buf := &bytes.Buffer{}
+	binary.Write(buf, binary.BigEndian, x)
+	return buf.Bytes()
+}
+
+func str(s string) []byte {
+	return []byte(s + "\x00")
+}
+
+//-----------------------------------------------------------------------------
+
+func testDTB32() []byte {
+	d := newDTB()
+	d.begin("")
+	d.prop("#address-cells", cells(1)).prop("#size-cells", cells(1))
+	d.prop("model", str("SiFive HiFive1"))
+	d.begin("cpus").prop("#address-cells", cells(1)).prop("#size-cells", cells(0))
+	d.begin("cpu@0").prop("device_type", str("cpu")).prop("reg", cells(0)).prop("riscv,isa", str("rv32imac")).end()
+	d.end()
+	d.begin("memory@80000000").prop("device_type", str("memory")).prop("reg", cells(0x80000000, 0x4000)).end()
+	d.begin("soc").prop("#address-cells", cells(1)).prop("#size-cells", cells(1))
+	d.begin("serial@10013000").prop("compatible", str("sifive,uart0")).prop("reg", cells(0x10013000, 0x1000)).end()
+	d.begin("serial@10023000").prop("compatible", str("sifive,uart0")).prop("reg", cells(0x10023000, 0x1000)).end()
+	d.end()
+	d.end()
+	return d.blob()
+}
+
+func testDTB64() []byte {
+	d := newDTB()
+	d.begin("")
+	d.prop("#address-cells", cells(2)).prop("#size-cells", cells(2))
+	d.prop("compatible", str("ucbbar,spike-bare\x00ucbbar,spike-bare-dev"))
+	d.begin("cpus").prop("#address-cells", cells(1)).prop("#size-cells", cells(0))
+	d.begin("cpu@1").prop("device_type", str("cpu")).prop("reg", cells(1)).prop("riscv,isa", str("rv64imafdc")).end()
+	d.begin("cpu@0").prop("device_type", str("cpu")).prop("reg", cells(0)).prop("riscv,isa", str("rv64imafdc")).end()
+	d.end()
+	d.begin("memory@80000000").prop("device_type", str("memory")).prop("reg", cells(0, 0x80000000, 1, 0)).end()
+	d.end()
+	return d.blob()
+}
+
+func Test_ParseFDT(t *testing.T) {
+	test := []struct {
+		buf []byte
+		cfg *Config
+	}{
+		{testDTB32(), &Config{
+			Format: "dtb",
+			Model:  "SiFive HiFive1",
+			Harts:  []Hart{{0, "rv32imac"}},
+			Regions: []Region{
+				{"serial", 0x10013000, 0x1000, "sifive,uart0"},
+				{"serial1", 0x10023000, 0x1000, "sifive,uart0"},
+				{"memory", 0x80000000, 0x4000, "memory"},
+			},
+		}},
+		{testDTB64(), &Config{
+			Format:  "dtb",
+			Model:   "ucbbar,spike-bare",
+			Harts:   []Hart{{0, "rv64imafdc"}, {1, "rv64imafdc"}},
+			Regions: []Region{{"memory", 0x80000000, 1 << 32, "memory"}},
+		}},
+	}
+	for i, v := range test {
+		cfg, err := Parse(v.buf)
+		if err != nil {
+			t.Errorf("test %d: unexpected error %v", i, err)
+			continue
+		}
+		if !reflect.DeepEqual(cfg, v.cfg) {
+			t.Errorf("test %d: expected %#v, actual %#v", i, v.cfg, cfg)
+		}
+	}
+}
+
+func Test_ParseFDTErrors(t *testing.T) {
+	good := testDTB32()
+	badMagic := append([]byte{}, good...)
+	badMagic[3] = 0
+	d := newDTB().begin("").prop("model", str("x")).end()
+	badToken := d.blob()
+	binary.BigEndian.PutUint32(badToken[40+d.st.Len()-4:], 0x55)
+	unbalanced := newDTB().begin("").prop("model", str("x")).end().end().blob()
+	d = newDTB().prop("model", str("x"))
+	d.st.Reset()
+	noRoot := d.blob()
+
+	test := []struct {
+		buf []byte
+		err error
+	}{
+		{good[:32], errors.New("dtb header is too short")},
+		{badMagic, errors.New("bad dtb magic")},
+		{badToken, errors.New("dtb has a bad token 0x55")},
+		{unbalanced, errors.New("dtb has unbalanced end node")},
+		{noRoot, errors.New("dtb has no root node")},
+	}
+	for i, v := range test {
+		_, err := parseFDT(v.buf)
+		if err == nil || err.Error() != v.err.Error() {
+			t.Errorf("test %d: expected error %v, actual %v", i, v.err, err)
+		}
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+const testConfigString = `
+platform {
+  vendor ucb;
+  arch spike;
+};
+ram {
+  0 {
+    addr 0x80000000;
+    size 0x10000000;
+  };
+};
+rtc {
+  addr 0x40000000;
+  size 0x1000;
+};
+core {
+  0 {
+    0 {
+      isa rv64imafdc;
+    };
+  };
+  1 {
+    0 {
+      isa rv64imac;
+    };
+  };
+};
+`
+
+func Test_ParseConfigString(t *testing.T) {
+	test := []struct {
+		s   string
+		cfg *Config
+	}{
+		{testConfigString, &Config{
+			Format: "config string",
+			Model:  "ucb spike",
+			Harts:  []Hart{{0, "rv64imafdc"}, {1, "rv64imac"}},
+			Regions: []Region{
+				{"rtc", 0x40000000, 0x1000, "rtc"},
+				{"ram0", 0x80000000, 0x10000000, "ram"},
+			},
+		}},
+		// top level addr/size keys are not a region
+		{"addr 0x1000; size 0x100; platform { vendor acme; };", &Config{
+			Format: "config string",
+			Model:  "acme",
+		}},
+	}
+	for i, v := range test {
+		cfg, err := Parse([]byte(v.s))
+		if err != nil {
+			t.Errorf("test %d: unexpected error %v", i, err)
+			continue
+		}
+		if !reflect.DeepEqual(cfg, v.cfg) {
+			t.Errorf("test %d: expected %#v, actual %#v", i, v.cfg, cfg)
+		}
+	}
+}
+
+func Test_ParseConfigStringErrors(t *testing.T) {
+	test := []struct {
+		s   string
+		err error
+	}{
+		{"", errors.New("config string is empty")},
+		{"vendor ucb;", errors.New("config string is empty")},
+		{"ram { 0 { addr 0x0; };", errors.New("config string has unbalanced braces")},
+		{"ram { };\n};", errors.New("config string has unbalanced braces")},
+		{"ram 0 { addr 0x0; };", errors.New("config string has a bad node name near \"ram\"")},
+	}
+	for i, v := range test {
+		_, err := parseConfigString(v.s)
+		if err == nil || err.Error() != v.err.Error() {
+			t.Errorf("test %d: expected error %v, actual %v", i, v.err, err)
+		}
+	}
+}
+
+//-----------------------------------------------------------------------------
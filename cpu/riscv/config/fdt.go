@@ -0,0 +1,229 @@
+//-----------------------------------------------------------------------------
+/*
+
+Flattened Device Tree Parsing
+
+See: https://www.devicetree.org/specifications/
+
+*/
+//-----------------------------------------------------------------------------
+
+package config
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+//-----------------------------------------------------------------------------
+
+const fdtMagic = 0xd00dfeed
+
+// structure block tokens
+const (
+	fdtBeginNode = 1
+	fdtEndNode   = 2
+	fdtProp      = 3
+	fdtNop       = 4
+	fdtEnd       = 9
+)
+
+//-----------------------------------------------------------------------------
+
+// fdtNode is a device tree node.
+type fdtNode struct {
+	name     string
+	prop     map[string][]byte
+	parent   *fdtNode
+	children []*fdtNode
+}
+
+// cells returns the value of a #address-cells/#size-cells property.
+func (n *fdtNode) cells(name string, dflt uint) uint {
+	if v, ok := n.prop[name]; ok && len(v) == 4 {
+		return uint(binary.BigEndian.Uint32(v))
+	}
+	return dflt
+}
+
+// str returns a string property (the first string of a string list).
+func (n *fdtNode) str(name string) string {
+	v, ok := n.prop[name]
+	if !ok {
+		return ""
+	}
+	return strings.SplitN(string(v), "\x00", 2)[0]
+}
+
+// baseName returns the node name without the unit address.
+func (n *fdtNode) baseName() string {
+	return strings.SplitN(n.name, "@", 2)[0]
+}
+
+// readCells reads an n-cell big-endian value from a buffer.
+func readCells(buf []byte, n uint) uint {
+	x := uint(0)
+	for i := uint(0); i < n; i++ {
+		x = (x << 32) | uint(binary.BigEndian.Uint32(buf[4*i:]))
+	}
+	return x
+}
+
+// reg returns the (address, size) pairs of the reg property.
+func (n *fdtNode) reg() [][2]uint {
+	v, ok := n.prop["reg"]
+	if !ok || n.parent == nil {
+		return nil
+	}
+	ac := n.parent.cells("#address-cells", 2)
+	sc := n.parent.cells("#size-cells", 1)
+	k := 4 * (ac + sc)
+	if k == 0 {
+		return nil
+	}
+	r := [][2]uint{}
+	for i := uint(0); i+k <= uint(len(v)); i += k {
+		addr := readCells(v[i:], ac)
+		size := readCells(v[i+4*ac:], sc)
+		r = append(r, [2]uint{addr, size})
+	}
+	return r
+}
+
+//-----------------------------------------------------------------------------
+
+// fdtUnflatten converts a device tree blob into a tree of nodes.
+func fdtUnflatten(buf []byte) (*fdtNode, error) {
+	if len(buf) < 40 {
+		return nil, errors.New("dtb header is too short")
+	}
+	rd32 := func(ofs uint) uint { return uint(binary.BigEndian.Uint32(buf[ofs:])) }
+	if rd32(0) != fdtMagic {
+		return nil, errors.New("bad dtb magic")
+	}
+	size := uint(len(buf))
+	structOfs := rd32(8)
+	stringsOfs := rd32(12)
+	if structOfs >= size || stringsOfs >= size {
+		return nil, errors.New("bad dtb block offsets")
+	}
+	// get a nul terminated string
+	getString := func(ofs uint) (string, error) {
+		if ofs >= size {
+			return "", errors.New("dtb string is out of bounds")
+		}
+		i := strings.IndexByte(string(buf[ofs:]), 0)
+		if i < 0 {
+			return "", errors.New("dtb string is not terminated")
+		}
+		return string(buf[ofs : ofs+uint(i)]), nil
+	}
+	align := func(x uint) uint { return (x + 3) &^ 3 }
+
+	var root, node *fdtNode
+	ofs := structOfs
+	for {
+		if ofs+4 > size {
+			return nil, errors.New("dtb structure block is truncated")
+		}
+		token := rd32(ofs)
+		ofs += 4
+		switch token {
+		case fdtBeginNode:
+			name, err := getString(ofs)
+			if err != nil {
+				return nil, err
+			}
+			ofs = align(ofs + uint(len(name)) + 1)
+			n := &fdtNode{
+				name:   name,
+				prop:   make(map[string][]byte),
+				parent: node,
+			}
+			if node == nil {
+				if root != nil {
+					return nil, errors.New("dtb has multiple root nodes")
+				}
+				root = n
+			} else {
+				node.children = append(node.children, n)
+			}
+			node = n
+		case fdtEndNode:
+			if node == nil {
+				return nil, errors.New("dtb has unbalanced end node")
+			}
+			node = node.parent
+		case fdtProp:
+			if node == nil || ofs+8 > size {
+				return nil, errors.New("dtb has a bad property")
+			}
+			length := rd32(ofs)
+			name, err := getString(stringsOfs + rd32(ofs+4))
+			if err != nil {
+				return nil, err
+			}
+			ofs += 8
+			if ofs+length > size {
+				return nil, fmt.Errorf("dtb property %s is out of bounds", name)
+			}
+			node.prop[name] = buf[ofs : ofs+length]
+			ofs = align(ofs + length)
+		case fdtNop:
+		case fdtEnd:
+			if root == nil {
+				return nil, errors.New("dtb has no root node")
+			}
+			return root, nil
+		default:
+			return nil, fmt.Errorf("dtb has a bad token 0x%x", token)
+		}
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+// parseFDT parses a device tree blob.
+func parseFDT(buf []byte) (*Config, error) {
+	root, err := fdtUnflatten(buf)
+	if err != nil {
+		return nil, err
+	}
+	cfg := &Config{
+		Format: "dtb",
+		Model:  root.str("model"),
+	}
+	if cfg.Model == "" {
+		cfg.Model = root.str("compatible")
+	}
+	var walk func(n *fdtNode)
+	walk = func(n *fdtNode) {
+		switch {
+		case n.str("device_type") == "cpu":
+			r := n.reg()
+			if len(r) != 0 {
+				cfg.Harts = append(cfg.Harts, Hart{ID: r[0][0], ISA: n.str("riscv,isa")})
+			}
+		case n.str("device_type") == "memory":
+			for _, r := range n.reg() {
+				cfg.addRegion(Region{Name: n.baseName(), Addr: r[0], Size: r[1], Descr: "memory"})
+			}
+		case n.parent != nil && n.baseName() != "cpus":
+			descr := n.str("compatible")
+			for _, r := range n.reg() {
+				if r[1] != 0 {
+					cfg.addRegion(Region{Name: n.baseName(), Addr: r[0], Size: r[1], Descr: descr})
+				}
+			}
+		}
+		for _, c := range n.children {
+			walk(c)
+		}
+	}
+	walk(root)
+	return cfg, nil
+}
+
+//-----------------------------------------------------------------------------
@@ -8,7 +8,7 @@ Implements the soc.Driver interface for the CPUs control and status registers.
 */
 //-----------------------------------------------------------------------------
 
-package gd32v
+package riscv
 
 import (
 	"errors"
@@ -19,31 +19,37 @@ import (
 
 //-----------------------------------------------------------------------------
 
-type csrDriver struct {
+// CsrDriver is a soc.Driver for the CSRs of the current hart.
+type CsrDriver struct {
 	dbg rv.Debug
 }
 
-func newCsrDriver(dbg rv.Debug) *csrDriver {
-	return &csrDriver{
+// NewCsrDriver returns a CSR driver.
+func NewCsrDriver(dbg rv.Debug) *CsrDriver {
+	return &CsrDriver{
 		dbg: dbg,
 	}
 }
 
-func (drv *csrDriver) GetAddressSize() uint {
+// GetAddressSize returns the CSR address size.
+func (drv *CsrDriver) GetAddressSize() uint {
 	// 12-bits for the CSR register number.
 	return 12
 }
 
-func (drv *csrDriver) GetRegisterSize(r *soc.Register) uint {
+// GetRegisterSize returns the size of a CSR for the current hart.
+func (drv *CsrDriver) GetRegisterSize(r *soc.Register) uint {
 	return rv.GetCSRSize(r.Offset, drv.dbg.GetCurrentHart())
 }
 
-func (drv *csrDriver) Rd(width, addr uint) (uint, error) {
+// Rd reads a CSR.
+func (drv *CsrDriver) Rd(width, addr uint) (uint, error) {
 	val, err := drv.dbg.RdCSR(addr, width)
 	return uint(val), err
 }
 
-func (drv *csrDriver) Wr(width, addr, val uint) error {
+// Wr writes a CSR.
+func (drv *CsrDriver) Wr(width, addr, val uint) error {
 	return errors.New("TODO")
 }
 
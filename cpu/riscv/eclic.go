@@ -0,0 +1,118 @@
+//-----------------------------------------------------------------------------
+/*
+
+Nuclei Enhanced Core Local Interrupt Controller (ECLIC)
+
+Each interrupt has 4 byte registers at 0x1000 + (4 * n):
+clicintip (pending), clicintie (enable), clicintattr (trigger/vectoring)
+and clicintctl (level/priority). The upper cliccfg.nlbits bits of the
+implemented clicintctl bits are the level, the remaining bits are priority.
+
+*/
+//-----------------------------------------------------------------------------
+
+package riscv
+
+import (
+	"fmt"
+
+	cli "github.com/deadsy/go-cli"
+	"github.com/deadsy/rvdbg/cpu/riscv/rv"
+	"github.com/deadsy/rvdbg/soc"
+	"github.com/deadsy/rvdbg/util"
+)
+
+//-----------------------------------------------------------------------------
+
+// ECLIC register offsets
+const (
+	eclicCfg  = 0x0    // cliccfg
+	eclicInfo = 0x4    // clicinfo
+	eclicMth  = 0xb    // mth
+	eclicInt  = 0x1000 // clicintip/ie/attr/ctl
+)
+
+// eclicInternal are the names of the core internal interrupts.
+var eclicInternal = map[uint]string{
+	3:  "msip",
+	7:  "mtip",
+	17: "bwei",
+	18: "pmovi",
+}
+
+var eclicTrig = [4]string{"level", "rising", "level", "falling"}
+
+// eclicLevel returns the level and priority for a clicintctl value.
+func eclicLevel(ctl, nlbits, ctlbits uint) (uint, uint) {
+	if nlbits > ctlbits {
+		nlbits = ctlbits
+	}
+	// unimplemented level bits read as ones
+	level := (ctl | ((1 << (8 - nlbits)) - 1)) & 0xff
+	prio := uint(0)
+	if nlbits < ctlbits {
+		prio = util.Bits(ctl, 7-nlbits, 8-ctlbits)
+	}
+	return level, prio
+}
+
+// eclicString returns the ECLIC state.
+func eclicString(dbg rv.Debug, dev *soc.Device, base uint) (string, error) {
+	cfg, err := dbg.RdMem(8, base+eclicCfg, 1)
+	if err != nil {
+		return "", err
+	}
+	info, err := rdMem32(dbg, base+eclicInfo)
+	if err != nil {
+		return "", err
+	}
+	x, err := dbg.RdMem(8, base+eclicMth, 1)
+	if err != nil {
+		return "", err
+	}
+	mth := x[0]
+	nlbits := util.Bits(cfg[0], 4, 1)
+	ctlbits := util.Bits(info, 24, 21)
+	n := util.Bits(info, 12, 0)
+	// read the per-interrupt registers
+	buf, err := dbg.RdMem(8, base+eclicInt, 4*n)
+	if err != nil {
+		return "", err
+	}
+	names := irqNames(dev)
+	for k, v := range eclicInternal {
+		names[k] = v
+	}
+	s := [][]string{}
+	s = append(s, []string{"irq", "name", "ie", "ip", "level", "prio", "trig", "shv", ""})
+	for i := uint(0); i < n; i++ {
+		ip := buf[4*i+0] & 1
+		ie := buf[4*i+1] & 1
+		attr := buf[4*i+2]
+		ctl := buf[4*i+3]
+		name, ok := names[i]
+		if !ok && ip == 0 && ie == 0 {
+			continue
+		}
+		level, prio := eclicLevel(ctl, nlbits, ctlbits)
+		note := ""
+		if ie != 0 && ip != 0 && level <= mth {
+			note = "masked by mth"
+		}
+		s = append(s, []string{
+			fmt.Sprintf("%d", i),
+			name,
+			fmt.Sprintf("%d", ie),
+			fmt.Sprintf("%d", ip),
+			fmt.Sprintf("%d", level),
+			fmt.Sprintf("%d", prio),
+			eclicTrig[util.Bits(attr, 2, 1)],
+			[]string{"no", "yes"}[attr&1],
+			note,
+		})
+	}
+	hdr := fmt.Sprintf("eclic @ 0x%x: %d interrupts, nlbits %d, clicintctlbits %d, mth %d\n", base, n, nlbits, ctlbits, mth)
+	return hdr + cli.TableString(s, []int{0, 0, 0, 0, 0, 0, 0, 0, 0}, 1), nil
+}
+
+//-----------------------------------------------------------------------------
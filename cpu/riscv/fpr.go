@@ -0,0 +1,299 @@
+//-----------------------------------------------------------------------------
+/*
+
+RISC-V Floating Point Registers
+
+Display and write the floating point register set.
+Register values are decoded as float32/float64 per FLEN. On a 64-bit FLEN
+a single precision value is NaN-boxed (the upper 32 bits are all ones).
+
+*/
+//-----------------------------------------------------------------------------
+
+package riscv
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	cli "github.com/deadsy/go-cli"
+	"github.com/deadsy/rvdbg/cpu/riscv/rv"
+	"github.com/deadsy/rvdbg/util"
+)
+
+//-----------------------------------------------------------------------------
+
+var abiFName = [32]string{
+	"ft0", "ft1", "ft2", "ft3", "ft4", "ft5", "ft6", "ft7",
+	"fs0", "fs1", "fa0", "fa1", "fa2", "fa3", "fa4", "fa5",
+	"fa6", "fa7", "fs2", "fs3", "fs4", "fs5", "fs6", "fs7",
+	"fs8", "fs9", "fs10", "fs11", "ft8", "ft9", "ft10", "ft11",
+}
+
+// fprNumber returns the register number for an ABI (fa0) or numeric (f10) FPR name.
+func fprNumber(name string) (uint, error) {
+	for i := range abiFName {
+		if name == abiFName[i] || name == fmt.Sprintf("f%d", i) {
+			return uint(i), nil
+		}
+	}
+	return 0, fmt.Errorf("no register \"%s\"", name)
+}
+
+// fprValue returns the flen-bit register value for a float or raw hex (0x...) argument.
+func fprValue(arg string, flen uint) (uint64, error) {
+	if strings.HasPrefix(arg, "0x") {
+		x, err := cli.UintArg(arg, [2]uint{0, util.Mask(flen-1, 0)}, 16)
+		return uint64(x), err
+	}
+	f, err := strconv.ParseFloat(arg, int(flen))
+	if err != nil {
+		return 0, fmt.Errorf("invalid floating point value \"%s\"", arg)
+	}
+	if flen == 32 {
+		return uint64(math.Float32bits(float32(f))), nil
+	}
+	return math.Float64bits(f), nil
+}
+
+//-----------------------------------------------------------------------------
+// decode
+
+// isNaNBoxed returns true if a 64-bit register value holds a NaN-boxed single.
+func isNaNBoxed(x uint64) bool {
+	return x>>32 == util.Mask32
+}
+
+// fprDecode returns the type and value strings for an flen-bit register value.
+func fprDecode(x uint64, flen uint) (string, string) {
+	if flen == 64 && !isNaNBoxed(x) {
+		return "f64", strconv.FormatFloat(math.Float64frombits(x), 'g', -1, 64)
+	}
+	f := float64(math.Float32frombits(uint32(x)))
+	return "f32", strconv.FormatFloat(f, 'g', -1, 32)
+}
+
+// fflagsString returns a string for the accrued exception flags.
+func fflagsString(x uint) string {
+	s := []string{}
+	for i := len(rv.FflagsName) - 1; i >= 0; i-- {
+		if x&(1<<uint(i)) != 0 {
+			s = append(s, rv.FflagsName[i])
+		}
+	}
+	if len(s) == 0 {
+		return "none"
+	}
+	return strings.Join(s, ",")
+}
+
+// frmString returns a string for the rounding mode.
+func frmString(x uint) string {
+	if s, ok := rv.RoundingMode[x]; ok {
+		return s
+	}
+	return fmt.Sprintf("reserved(%d)", x)
+}
+
+// fcsrString returns a string for the fcsr value.
+func fcsrString(x uint) string {
+	frm := util.Bits(x, 7, 5)
+	fflags := util.Bits(x, 4, 0)
+	return fmt.Sprintf("%-9s %08x frm %s fflags %s", "fcsr", x, frmString(frm), fflagsString(fflags))
+}
+
+//-----------------------------------------------------------------------------
+// display
+
+var fprCache []uint64
+
+func fprString(reg []uint64, flen uint) string {
+	fmtx := "%08x"
+	if flen == 64 {
+		fmtx = "%016x"
+	}
+	if fprCache == nil {
+		fprCache = reg
+	}
+	s := make([]string, len(reg))
+	for i := 0; i < len(reg); i++ {
+		delta := ""
+		if reg[i] != fprCache[i] {
+			delta = " *"
+		}
+		regStr := fmt.Sprintf("f%d", i)
+		valStr := fmt.Sprintf(fmtx, reg[i])
+		typ, f := fprDecode(reg[i], flen)
+		if flen == 64 && typ == "f32" {
+			f += " (f32)"
+		}
+		s[i] = fmt.Sprintf("%-4s %-4s %s %s%s", regStr, abiFName[i], valStr, f, delta)
+	}
+	fprCache = reg
+	return strings.Join(s, "\n")
+}
+
+//-----------------------------------------------------------------------------
+// json
+
+type fprJSON struct {
+	Reg   string `json:"reg"`
+	ABI   string `json:"abi"`
+	Raw   string `json:"raw"`
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+type fcsrJSON struct {
+	Raw    string   `json:"raw"`
+	Frm    string   `json:"frm"`
+	Fflags []string `json:"fflags"`
+}
+
+type fpuJSON struct {
+	Hart int       `json:"hart"`
+	FLEN uint      `json:"flen"`
+	FPR  []fprJSON `json:"fpr"`
+	FCSR *fcsrJSON `json:"fcsr,omitempty"`
+}
+
+// fprJSONString returns a JSON string for the floating point register file.
+func fprJSONString(hi *rv.HartInfo, reg []uint64, fcsr *uint) (string, error) {
+	x := fpuJSON{
+		Hart: hi.ID,
+		FLEN: hi.FLEN,
+	}
+	for i := range reg {
+		typ, val := fprDecode(reg[i], hi.FLEN)
+		x.FPR = append(x.FPR, fprJSON{
+			Reg:   fmt.Sprintf("f%d", i),
+			ABI:   abiFName[i],
+			Raw:   fmt.Sprintf("0x%x", reg[i]),
+			Type:  typ,
+			Value: val,
+		})
+	}
+	if fcsr != nil {
+		flags := []string{}
+		for i, name := range rv.FflagsName {
+			if *fcsr&(1<<uint(i)) != 0 {
+				flags = append(flags, name)
+			}
+		}
+		x.FCSR = &fcsrJSON{
+			Raw:    fmt.Sprintf("0x%x", *fcsr),
+			Frm:    frmString(util.Bits(*fcsr, 7, 5)),
+			Fflags: flags,
+		}
+	}
+	buf, err := json.MarshalIndent(&x, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(buf), nil
+}
+
+//-----------------------------------------------------------------------------
+
+// FprHelp is help information for the "fpr" command.
+var FprHelp = []cli.Help{
+	{"<cr>", "display all registers"},
+	{"json", "display all registers as json"},
+	{"<reg>", "display a register"},
+	{"<reg> <value>", "write a register"},
+	{"  reg", "register name (fa0, f10)"},
+	{"  value", "floating point value (1.5) or raw bits (0x3fc00000)"},
+}
+
+// CmdFpr displays and writes the floating point registers.
+var CmdFpr = cli.Leaf{
+	Descr: "display/write floating point registers",
+	F: func(c *cli.CLI, args []string) {
+		err := cli.CheckArgc(args, []int{0, 1, 2})
+		if err != nil {
+			c.User.Put(fmt.Sprintf("%s\n", err))
+			return
+		}
+		dbg := c.User.(target).GetRiscvDebug()
+		hi := dbg.GetCurrentHart()
+		if hi.FLEN == 0 {
+			c.User.Put(fmt.Sprintf("hart%d has no floating point registers\n", hi.ID))
+			return
+		}
+		err = dbg.HaltHart()
+		if err != nil {
+			c.User.Put(fmt.Sprintf("unable to halt hart%d: %v\n", hi.ID, err))
+			return
+		}
+		if len(args) == 2 && args[0] == "json" {
+			c.User.Put("too many arguments\n")
+			return
+		}
+		if len(args) != 0 && args[0] != "json" {
+			name := strings.ToLower(args[0])
+			reg, err := fprNumber(name)
+			if err != nil {
+				c.User.Put(fmt.Sprintf("%s\n", err))
+				return
+			}
+			if len(args) == 2 {
+				val, err := fprValue(args[1], hi.FLEN)
+				if err != nil {
+					c.User.Put(fmt.Sprintf("%s\n", err))
+					return
+				}
+				err = dbg.WrFPR(reg, 0, val)
+				if err != nil {
+					c.User.Put(fmt.Sprintf("unable to write %s: %v\n", name, err))
+				}
+				return
+			}
+			x, err := dbg.RdFPR(reg, 0)
+			if err != nil {
+				c.User.Put(fmt.Sprintf("unable to read %s: %v\n", name, err))
+				return
+			}
+			_, f := fprDecode(x, hi.FLEN)
+			c.User.Put(fmt.Sprintf("f%d %s "+util.UintFormat(hi.FLEN)+" %s\n", reg, abiFName[reg], x, f))
+			return
+		}
+		// slice of register values
+		reg := make([]uint64, hi.Nregs)
+		// read the FPRs
+		for i := 0; i < hi.Nregs; i++ {
+			var err error
+			reg[i], err = dbg.RdFPR(uint(i), 0)
+			if err != nil {
+				c.User.Put(fmt.Sprintf("unable to read fpr%d: %v\n", i, err))
+				return
+			}
+		}
+		// read the fcsr
+		var fcsr *uint
+		x, err := dbg.RdCSR(rv.FCSR, 0)
+		if err == nil {
+			v := uint(x)
+			fcsr = &v
+		}
+		if len(args) == 1 {
+			s, err := fprJSONString(hi, reg, fcsr)
+			if err != nil {
+				c.User.Put(fmt.Sprintf("%s\n", err))
+				return
+			}
+			c.User.Put(fmt.Sprintf("%s\n", s))
+			return
+		}
+		c.User.Put(fmt.Sprintf("%s\n", fprString(reg, hi.FLEN)))
+		if fcsr != nil {
+			c.User.Put(fmt.Sprintf("%s\n", fcsrString(*fcsr)))
+		} else {
+			c.User.Put(fmt.Sprintf("unable to read fcsr: %v\n", err))
+		}
+	},
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,984 @@
+//-----------------------------------------------------------------------------
+/*
+
+GDB Remote Serial Protocol Server
+
+gdb connects over TCP ("target extended-remote :3333") and the debugger
+serves the remote protocol using the rv.Debug interface for registers,
+run control and breakpoints and the target memory driver for memory.
+
+The session is all-stop. When one hart halts the other harts are halted.
+Debug modules without a group halt (0.11, 0.13 without hasel) halt the other
+harts one at a time, so they stop a few instructions apart.
+Harts are RSP threads, the thread id is the hart id + 1.
+
+Breakpoints and watchpoints are set on all harts. Software breakpoints patch
+memory shared by the harts, so they are only used on single hart targets.
+
+The CLI is blocked while gdb is connected. ctrl-d ends the session.
+
+*/
+//-----------------------------------------------------------------------------
+
+package riscv
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	cli "github.com/deadsy/go-cli"
+	"github.com/deadsy/rvdbg/cpu/riscv/rv"
+	"github.com/deadsy/rvdbg/mem"
+	"github.com/deadsy/rvdbg/util"
+	"github.com/deadsy/rvdbg/util/log"
+)
+
+//-----------------------------------------------------------------------------
+
+const gdbDefaultAddr = ":3333"
+
+// gdbPacketSize is the maximum packet size.
+const gdbPacketSize = 0x4000
+
+// stop signals
+const (
+	gdbSigINT  = 2
+	gdbSigTRAP = 5
+)
+
+// gdbWatchStop is the stop reply reason for a watchpoint.
+var gdbWatchStop = map[rv.WatchType]string{
+	rv.WatchWrite:     "watch",
+	rv.WatchRead:      "rwatch",
+	rv.WatchReadWrite: "awatch",
+}
+
+// gdbWatchType is the watchpoint type for a Z2/Z3/Z4 packet.
+var gdbWatchType = map[byte]rv.WatchType{
+	'2': rv.WatchWrite,
+	'3': rv.WatchRead,
+	'4': rv.WatchReadWrite,
+}
+
+var errGdbPacket = errors.New("bad packet")
+
+//-----------------------------------------------------------------------------
+// packet encoding
+
+func gdbChecksum(data []byte) uint8 {
+	sum := uint8(0)
+	for _, c := range data {
+		sum += c
+	}
+	return sum
+}
+
+// gdbEscape escapes the special characters in a packet.
+func gdbEscape(s string) []byte {
+	x := []byte{}
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '#', '$', '}', '*':
+			x = append(x, '}', c^0x20)
+		default:
+			x = append(x, c)
+		}
+	}
+	return x
+}
+
+// gdbUnescape removes the escapes from a packet.
+func gdbUnescape(data []byte) string {
+	x := []byte{}
+	for i := 0; i < len(data); i++ {
+		if data[i] == '}' && i+1 < len(data) {
+			i++
+			x = append(x, data[i]^0x20)
+		} else {
+			x = append(x, data[i])
+		}
+	}
+	return string(x)
+}
+
+// gdbHex returns the little-endian hex string for a register value.
+func gdbHex(val uint64, bits uint) string {
+	x := make([]byte, bits>>3)
+	for i := range x {
+		x[i] = uint8(val >> (8 * uint(i)))
+	}
+	return hex.EncodeToString(x)
+}
+
+// gdbUnhex returns a register value from a little-endian hex string.
+func gdbUnhex(s string) (uint64, error) {
+	x, err := hex.DecodeString(s)
+	if err != nil || len(x) > 8 {
+		return 0, errGdbPacket
+	}
+	val := uint64(0)
+	for i := range x {
+		val |= uint64(x[i]) << (8 * uint(i))
+	}
+	return val, nil
+}
+
+// gdbUint parses a hex number.
+func gdbUint(s string) (uint, error) {
+	x, err := strconv.ParseUint(s, 16, 64)
+	if err != nil {
+		return 0, errGdbPacket
+	}
+	return uint(x), nil
+}
+
+// gdbAddrLen parses an "addr,length" argument.
+func gdbAddrLen(s string) (uint, uint, error) {
+	x := strings.Split(s, ",")
+	if len(x) != 2 {
+		return 0, 0, errGdbPacket
+	}
+	addr, err := gdbUint(x[0])
+	if err != nil {
+		return 0, 0, err
+	}
+	n, err := gdbUint(x[1])
+	if err != nil {
+		return 0, 0, err
+	}
+	return addr, n, nil
+}
+
+//-----------------------------------------------------------------------------
+
+// gdbServer is the state for a gdb session.
+type gdbServer struct {
+	dbg     rv.Debug
+	drv     mem.Driver
+	user    cli.USER
+	conn    net.Conn
+	rx      chan []byte   // received data
+	quit    chan struct{} // stop the receiver
+	pkt     []byte        // partial packet (nil == not in a packet)
+	noAck   bool          // no acknowledgements
+	running bool          // the harts are running
+	done    bool          // the session is over
+	err     error         // connection error
+	hart    int           // hart for register and memory access
+	step    int           // hart to single step (-1 == current)
+}
+
+func newGdbServer(dbg rv.Debug, drv mem.Driver, user cli.USER, conn net.Conn) *gdbServer {
+	s := &gdbServer{
+		dbg:  dbg,
+		drv:  drv,
+		user: user,
+		conn: conn,
+		rx:   make(chan []byte),
+		quit: make(chan struct{}),
+		hart: dbg.GetCurrentHart().ID,
+		step: -1,
+	}
+	go s.receive()
+	return s
+}
+
+// receive reads data from the gdb connection.
+func (s *gdbServer) receive() {
+	buf := make([]byte, 4096)
+	for {
+		n, err := s.conn.Read(buf)
+		if n > 0 {
+			select {
+			case s.rx <- append([]byte{}, buf[:n]...):
+			case <-s.quit:
+				return
+			}
+		}
+		if err != nil {
+			close(s.rx)
+			return
+		}
+	}
+}
+
+// close closes the gdb connection.
+func (s *gdbServer) close() {
+	close(s.quit)
+	s.conn.Close()
+}
+
+// write writes data to the gdb connection.
+func (s *gdbServer) write(data []byte) {
+	_, err := s.conn.Write(data)
+	if err != nil && s.err == nil {
+		s.err = err
+	}
+}
+
+// send sends a packet to gdb.
+func (s *gdbServer) send(pkt string) {
+	x := gdbEscape(pkt)
+	s.write([]byte(fmt.Sprintf("$%s#%02x", x, gdbChecksum(x))))
+}
+
+// rxByte processes a received byte.
+func (s *gdbServer) rxByte(c byte) {
+	if s.pkt == nil {
+		switch c {
+		case '$':
+			s.pkt = []byte{}
+		case 0x03:
+			s.interrupt()
+		}
+		// ignore acknowledgements
+		return
+	}
+	s.pkt = append(s.pkt, c)
+	// $<data>#<checksum>
+	n := len(s.pkt)
+	if n < 3 || s.pkt[n-3] != '#' {
+		return
+	}
+	data, sum := s.pkt[:n-3], string(s.pkt[n-2:])
+	s.pkt = nil
+	if !s.noAck {
+		x, err := strconv.ParseUint(sum, 16, 8)
+		if err != nil || uint8(x) != gdbChecksum(data) {
+			s.write([]byte("-"))
+			return
+		}
+		s.write([]byte("+"))
+	}
+	s.handle(gdbUnescape(data))
+}
+
+// poll services the gdb session. It returns true when the session is over.
+func (s *gdbServer) poll() bool {
+	select {
+	case data, ok := <-s.rx:
+		if !ok {
+			// gdb closed the connection
+			s.done = true
+			break
+		}
+		for _, c := range data {
+			s.rxByte(c)
+		}
+	case <-time.After(10 * time.Millisecond):
+		if s.running {
+			s.checkHalt()
+		}
+	}
+	return s.done || s.err != nil
+}
+
+//-----------------------------------------------------------------------------
+// hart control
+
+// forEachHart runs a function for each hart.
+// The current hart is restored afterwards.
+func (s *gdbServer) forEachHart(f func(hi *rv.HartInfo) error) error {
+	defer s.dbg.SetCurrentHart(s.hart)
+	for i := 0; i < s.dbg.GetHartCount(); i++ {
+		hi, err := s.dbg.SetCurrentHart(i)
+		if err != nil {
+			return err
+		}
+		err = f(hi)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// haltAll halts all harts and selects a hart for register access.
+func (s *gdbServer) haltAll(id int) error {
+	s.running = false
+	together, err := s.dbg.HaltAll()
+	if err != nil {
+		return err
+	}
+	if !together {
+		log.Info.Printf("gdb: harts halted one at a time")
+	}
+	s.hart = id
+	_, err = s.dbg.SetCurrentHart(id)
+	return err
+}
+
+// stopReply returns the stop reply for the current hart.
+func (s *gdbServer) stopReply(sig int) (string, error) {
+	hi := s.dbg.GetCurrentHart()
+	reply := fmt.Sprintf("T%02xthread:%x;", sig, hi.ID+1)
+	cause, err := rv.GetHaltCause(s.dbg)
+	if err != nil {
+		return "", err
+	}
+	if cause == rv.CauseTrigger {
+		wp, err := rv.HitWatchpoint(s.dbg)
+		if err != nil {
+			return "", err
+		}
+		if wp != nil {
+			if reason, ok := gdbWatchStop[wp.Type]; ok {
+				reply += fmt.Sprintf("%s:%x;", reason, wp.Addr)
+			}
+		}
+	}
+	return reply, nil
+}
+
+// checkHalt checks the running harts for a halt.
+func (s *gdbServer) checkHalt() {
+	for i := 0; i < s.dbg.GetHartCount(); i++ {
+		_, err := s.dbg.SetCurrentHart(i)
+		if err != nil {
+			s.err = err
+			return
+		}
+		state, err := s.dbg.GetHartState()
+		if err != nil {
+			s.err = err
+			return
+		}
+		if state != rv.Halted {
+			continue
+		}
+		if semihosting.enabled {
+			resumed, msg, err := semihosting.service(s.dbg, s.user)
+			if err != nil {
+				s.err = err
+				return
+			}
+			if resumed {
+				continue
+			}
+			if msg != "" {
+				s.user.Put(fmt.Sprintf("%s\n", msg))
+			}
+		}
+		err = s.haltAll(i)
+		if err != nil {
+			s.err = err
+			return
+		}
+		reply, err := s.stopReply(gdbSigTRAP)
+		if err != nil {
+			s.err = err
+			return
+		}
+		s.send(reply)
+		return
+	}
+	s.dbg.SetCurrentHart(s.hart)
+}
+
+// interrupt halts the running harts.
+func (s *gdbServer) interrupt() {
+	if !s.running {
+		return
+	}
+	err := s.haltAll(s.hart)
+	if err != nil {
+		s.err = err
+		return
+	}
+	s.send(fmt.Sprintf("T%02xthread:%x;", gdbSigINT, s.hart+1))
+}
+
+// setPC sets the pc for a c/s packet with an address.
+func (s *gdbServer) setPC(args string) error {
+	if args == "" {
+		return nil
+	}
+	addr, err := gdbUint(args)
+	if err != nil {
+		return err
+	}
+	return s.dbg.WrCSR(rv.DPC, 0, uint64(addr))
+}
+
+// resume resumes all harts.
+func (s *gdbServer) resume() error {
+	return s.forEachHart(func(hi *rv.HartInfo) error {
+		err := rv.StepOverBreakpoint(s.dbg)
+		if err != nil {
+			return err
+		}
+		return s.dbg.ResumeHart()
+	})
+}
+
+// cont handles a continue packet. The stop reply is sent when a hart halts.
+func (s *gdbServer) cont(args string) error {
+	err := s.setPC(args)
+	if err != nil {
+		return err
+	}
+	err = s.resume()
+	if err != nil {
+		return err
+	}
+	s.running = true
+	return nil
+}
+
+// stepHart handles a single step packet.
+func (s *gdbServer) stepHart(args string) (string, error) {
+	if s.step >= 0 && s.step != s.hart {
+		s.hart = s.step
+		_, err := s.dbg.SetCurrentHart(s.hart)
+		if err != nil {
+			return "", err
+		}
+	}
+	err := s.setPC(args)
+	if err != nil {
+		return "", err
+	}
+	err = rv.Step(s.dbg, false)
+	if err != nil {
+		return "", err
+	}
+	return s.stopReply(gdbSigTRAP)
+}
+
+// detach resumes the harts and ends the session.
+func (s *gdbServer) detach() error {
+	s.done = true
+	return s.resume()
+}
+
+//-----------------------------------------------------------------------------
+// threads
+
+// setThread handles an H packet.
+func (s *gdbServer) setThread(args string) error {
+	if len(args) < 2 {
+		return errGdbPacket
+	}
+	tid, err := strconv.ParseInt(args[1:], 16, 32)
+	if err != nil {
+		return errGdbPacket
+	}
+	// 0 == any thread, -1 == all threads
+	id := int(tid) - 1
+	if tid > 0 && id >= s.dbg.GetHartCount() {
+		return fmt.Errorf("no hart%d", id)
+	}
+	switch args[0] {
+	case 'g':
+		if tid > 0 {
+			s.hart = id
+			_, err := s.dbg.SetCurrentHart(id)
+			return err
+		}
+	case 'c':
+		s.step = -1
+		if tid > 0 {
+			s.step = id
+		}
+	default:
+		return errGdbPacket
+	}
+	return nil
+}
+
+// threadAlive handles a T packet.
+func (s *gdbServer) threadAlive(args string) error {
+	tid, err := gdbUint(args)
+	if err != nil {
+		return err
+	}
+	if tid == 0 || int(tid) > s.dbg.GetHartCount() {
+		return fmt.Errorf("no thread %d", tid)
+	}
+	return nil
+}
+
+// threadList returns the thread list.
+func (s *gdbServer) threadList() string {
+	x := []string{}
+	for i := 0; i < s.dbg.GetHartCount(); i++ {
+		x = append(x, fmt.Sprintf("%x", i+1))
+	}
+	return "m" + strings.Join(x, ",")
+}
+
+//-----------------------------------------------------------------------------
+// registers
+
+// rdReg reads a register using the gdb register number.
+func (s *gdbServer) rdReg(n uint) (string, error) {
+	hi := s.dbg.GetCurrentHart()
+	switch {
+	case n < uint(hi.Nregs):
+		x, err := s.dbg.RdGPR(n, 0)
+		return gdbHex(x, hi.MXLEN), err
+	case n == gdbRegPC:
+		x, err := s.dbg.RdCSR(rv.DPC, 0)
+		return gdbHex(x, hi.MXLEN), err
+	case n >= gdbRegF0 && n < gdbRegF0+32 && hi.FLEN != 0:
+		x, err := s.dbg.RdFPR(n-gdbRegF0, 0)
+		return gdbHex(x, hi.FLEN), err
+	case n >= gdbRegCSR && n < gdbRegCSR+4096:
+		reg := n - gdbRegCSR
+		x, err := s.dbg.RdCSR(reg, 0)
+		return gdbHex(x, rv.GetCSRSize(reg, hi)), err
+	}
+	return "", fmt.Errorf("no register %d", n)
+}
+
+// wrReg writes a register using the gdb register number.
+func (s *gdbServer) wrReg(n uint, val uint64) error {
+	hi := s.dbg.GetCurrentHart()
+	switch {
+	case n == rv.RegZero:
+		return nil
+	case n < uint(hi.Nregs):
+		return s.dbg.WrGPR(n, 0, val)
+	case n == gdbRegPC:
+		return s.dbg.WrCSR(rv.DPC, 0, val)
+	case n >= gdbRegF0 && n < gdbRegF0+32 && hi.FLEN != 0:
+		return s.dbg.WrFPR(n-gdbRegF0, 0, val)
+	case n >= gdbRegCSR && n < gdbRegCSR+4096:
+		return s.dbg.WrCSR(n-gdbRegCSR, 0, val)
+	}
+	return fmt.Errorf("no register %d", n)
+}
+
+// rdRegs handles a g packet (the gprs and pc).
+func (s *gdbServer) rdRegs() (string, error) {
+	hi := s.dbg.GetCurrentHart()
+	x := []string{}
+	for i := uint(0); i < uint(hi.Nregs); i++ {
+		val, err := s.rdReg(i)
+		if err != nil {
+			return "", err
+		}
+		x = append(x, val)
+	}
+	val, err := s.rdReg(gdbRegPC)
+	if err != nil {
+		return "", err
+	}
+	x = append(x, val)
+	return strings.Join(x, ""), nil
+}
+
+// wrRegs handles a G packet (the gprs and pc).
+func (s *gdbServer) wrRegs(args string) error {
+	hi := s.dbg.GetCurrentHart()
+	n := int(hi.MXLEN >> 2)
+	if len(args) != (hi.Nregs+1)*n {
+		return errGdbPacket
+	}
+	for i := 0; i <= hi.Nregs; i++ {
+		val, err := gdbUnhex(args[i*n : (i+1)*n])
+		if err != nil {
+			return err
+		}
+		reg := uint(i)
+		if i == hi.Nregs {
+			reg = gdbRegPC
+		}
+		err = s.wrReg(reg, val)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rdRegPacket handles a p packet.
+func (s *gdbServer) rdRegPacket(args string) (string, error) {
+	n, err := gdbUint(args)
+	if err != nil {
+		return "", err
+	}
+	return s.rdReg(n)
+}
+
+// wrRegPacket handles a P packet.
+func (s *gdbServer) wrRegPacket(args string) error {
+	x := strings.Split(args, "=")
+	if len(x) != 2 {
+		return errGdbPacket
+	}
+	n, err := gdbUint(x[0])
+	if err != nil {
+		return err
+	}
+	val, err := gdbUnhex(x[1])
+	if err != nil {
+		return err
+	}
+	return s.wrReg(n, val)
+}
+
+//-----------------------------------------------------------------------------
+// memory
+
+// rdMem handles an m packet.
+func (s *gdbServer) rdMem(args string) (string, error) {
+	addr, n, err := gdbAddrLen(args)
+	if err != nil {
+		return "", err
+	}
+	n = min(n, gdbPacketSize>>1)
+	if n == 0 {
+		return "", nil
+	}
+	x, err := s.drv.RdMem(8, addr, n)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(util.CastUintto8(x)), nil
+}
+
+// wrMem handles an M packet.
+func (s *gdbServer) wrMem(args string) error {
+	x := strings.Split(args, ":")
+	if len(x) != 2 {
+		return errGdbPacket
+	}
+	addr, n, err := gdbAddrLen(x[0])
+	if err != nil {
+		return err
+	}
+	data, err := hex.DecodeString(x[1])
+	if err != nil || uint(len(data)) != n {
+		return errGdbPacket
+	}
+	if n == 0 {
+		return nil
+	}
+	val := make([]uint, n)
+	for i := range val {
+		val[i] = uint(data[i])
+	}
+	return s.drv.WrMem(8, addr, val)
+}
+
+//-----------------------------------------------------------------------------
+// breakpoints and watchpoints
+
+// addBreakpoint adds a breakpoint to all harts.
+func (s *gdbServer) addBreakpoint(addr uint, typ rv.BreakType) error {
+	err := s.forEachHart(func(hi *rv.HartInfo) error {
+		_, err := rv.AddBreakpoint(s.dbg, addr, typ)
+		return err
+	})
+	if err != nil {
+		// don't leave a partial breakpoint
+		s.removeBreakpoint(addr)
+	}
+	return err
+}
+
+// removeBreakpoint removes a breakpoint from all harts.
+func (s *gdbServer) removeBreakpoint(addr uint) error {
+	return s.forEachHart(func(hi *rv.HartInfo) error {
+		for _, bp := range hi.Breakpoints {
+			if bp.Addr == addr {
+				return rv.RemoveBreakpoint(s.dbg, bp.ID)
+			}
+		}
+		return nil
+	})
+}
+
+// addWatchpoint adds a watchpoint to all harts.
+func (s *gdbServer) addWatchpoint(typ rv.WatchType, addr, size uint) error {
+	err := s.forEachHart(func(hi *rv.HartInfo) error {
+		_, err := rv.AddWatchpoint(s.dbg, typ, addr, size, "")
+		return err
+	})
+	if err != nil {
+		// don't leave a partial watchpoint
+		s.removeWatchpoint(typ, addr, size)
+	}
+	return err
+}
+
+// removeWatchpoint removes a watchpoint from all harts.
+func (s *gdbServer) removeWatchpoint(typ rv.WatchType, addr, size uint) error {
+	return s.forEachHart(func(hi *rv.HartInfo) error {
+		for _, wp := range hi.Watchpoints {
+			if wp.Type == typ && wp.Addr == addr && wp.Size == size {
+				return rv.RemoveWatchpoint(s.dbg, wp.ID)
+			}
+		}
+		return nil
+	})
+}
+
+// breakpoint handles a Z/z packet.
+// It returns false if the breakpoint type is not supported.
+func (s *gdbServer) breakpoint(pkt string) (bool, error) {
+	x := strings.Split(pkt[1:], ",")
+	if len(x) != 3 || len(x[0]) != 1 {
+		return true, errGdbPacket
+	}
+	addr, err := gdbUint(x[1])
+	if err != nil {
+		return true, err
+	}
+	kind, err := gdbUint(x[2])
+	if err != nil {
+		return true, err
+	}
+	insert := pkt[0] == 'Z'
+	switch x[0][0] {
+	case '0', '1':
+		if !insert {
+			return true, s.removeBreakpoint(addr)
+		}
+		typ := rv.BreakHardware
+		if x[0][0] == '0' && s.dbg.GetHartCount() == 1 {
+			typ = rv.BreakAuto
+		}
+		return true, s.addBreakpoint(addr, typ)
+	case '2', '3', '4':
+		typ := gdbWatchType[x[0][0]]
+		if !insert {
+			return true, s.removeWatchpoint(typ, addr, kind)
+		}
+		return true, s.addWatchpoint(typ, addr, kind)
+	}
+	return false, nil
+}
+
+//-----------------------------------------------------------------------------
+// queries
+
+// xferFeatures handles a qXfer:features:read packet.
+func (s *gdbServer) xferFeatures(args string) (string, error) {
+	x := strings.Split(args, ":")
+	if len(x) != 2 {
+		return "", errGdbPacket
+	}
+	if x[0] != "target.xml" {
+		return "", fmt.Errorf("no annex \"%s\"", x[0])
+	}
+	ofs, n, err := gdbAddrLen(x[1])
+	if err != nil {
+		return "", err
+	}
+	xml := gdbTargetXML(s.dbg.GetCurrentHart())
+	if ofs >= uint(len(xml)) {
+		return "l", nil
+	}
+	end := ofs + min(n, gdbPacketSize-16)
+	if end >= uint(len(xml)) {
+		return "l" + xml[ofs:], nil
+	}
+	return "m" + xml[ofs:end], nil
+}
+
+// query handles q/Q packets.
+func (s *gdbServer) query(pkt string) (string, error) {
+	name, args := pkt, ""
+	if i := strings.IndexAny(pkt, ":,"); i >= 0 {
+		name, args = pkt[:i], pkt[i+1:]
+	}
+	switch name {
+	case "qSupported":
+		return fmt.Sprintf("PacketSize=%x;qXfer:features:read+;QStartNoAckMode+", gdbPacketSize), nil
+	case "QStartNoAckMode":
+		// the OK is sent with acknowledgements
+		s.send("OK")
+		s.noAck = true
+		return "", errGdbNoReply
+	case "qXfer":
+		if !strings.HasPrefix(args, "features:read:") {
+			return "", nil
+		}
+		return s.xferFeatures(strings.TrimPrefix(args, "features:read:"))
+	case "qAttached":
+		return "1", nil
+	case "qC":
+		return fmt.Sprintf("QC%x", s.hart+1), nil
+	case "qfThreadInfo":
+		return s.threadList(), nil
+	case "qsThreadInfo":
+		return "l", nil
+	case "qThreadExtraInfo":
+		tid, err := gdbUint(args)
+		if err != nil {
+			return "", err
+		}
+		hi, err := s.dbg.GetHartInfo(int(tid) - 1)
+		if err != nil {
+			return "", err
+		}
+		return hex.EncodeToString([]byte(fmt.Sprintf("hart%d %s", hi.ID, hi.State))), nil
+	}
+	// not supported
+	return "", nil
+}
+
+//-----------------------------------------------------------------------------
+
+// errGdbNoReply is returned by packet handlers that reply later (or never).
+var errGdbNoReply = errors.New("no reply")
+
+// dispatch handles a packet and returns the reply.
+func (s *gdbServer) dispatch(pkt string) (string, error) {
+	if s.running {
+		// only an interrupt is expected while the harts are running
+		return "", errGdbNoReply
+	}
+	switch pkt[0] {
+	case '?':
+		return s.stopReply(gdbSigTRAP)
+	case 'c':
+		err := s.cont(pkt[1:])
+		if err != nil {
+			return "", err
+		}
+		return "", errGdbNoReply
+	case 'D':
+		return "OK", s.detach()
+	case 'g':
+		return s.rdRegs()
+	case 'G':
+		return "OK", s.wrRegs(pkt[1:])
+	case 'H':
+		return "OK", s.setThread(pkt[1:])
+	case 'k':
+		s.done = true
+		return "", errGdbNoReply
+	case 'm':
+		return s.rdMem(pkt[1:])
+	case 'M':
+		return "OK", s.wrMem(pkt[1:])
+	case 'p':
+		return s.rdRegPacket(pkt[1:])
+	case 'P':
+		return "OK", s.wrRegPacket(pkt[1:])
+	case 'q', 'Q':
+		return s.query(pkt)
+	case 's':
+		return s.stepHart(pkt[1:])
+	case 'T':
+		return "OK", s.threadAlive(pkt[1:])
+	case 'Z', 'z':
+		ok, err := s.breakpoint(pkt)
+		if !ok {
+			return "", nil
+		}
+		return "OK", err
+	}
+	// not supported
+	return "", nil
+}
+
+// handle handles a packet from gdb.
+func (s *gdbServer) handle(pkt string) {
+	if pkt == "" {
+		s.send("")
+		return
+	}
+	reply, err := s.dispatch(pkt)
+	if err == errGdbNoReply {
+		return
+	}
+	if err != nil {
+		log.Debug.Printf("gdb packet \"%s\": %v", pkt, err)
+		s.send("E01")
+		return
+	}
+	s.send(reply)
+}
+
+//-----------------------------------------------------------------------------
+
+// gdbAccept is called repeatedly until gdb connects.
+func gdbAccept(ln *net.TCPListener, conn *net.Conn, err *error) bool {
+	ln.SetDeadline(time.Now().Add(100 * time.Millisecond))
+	c, e := ln.Accept()
+	if e != nil {
+		if ne, ok := e.(net.Error); ok && ne.Timeout() {
+			return false
+		}
+		*err = e
+		return true
+	}
+	*conn = c
+	return true
+}
+
+// memTarget provides a method for getting the memory driver.
+type memTarget interface {
+	GetMemoryDriver() mem.Driver
+}
+
+// GdbHelp is help information for the "gdb" command.
+var GdbHelp = []cli.Help{
+	{"[addr]", "listen address (host:port), default is :3333"},
+}
+
+// CmdGdb runs a gdb remote serial protocol server.
+var CmdGdb = cli.Leaf{
+	Descr: "gdb remote serial protocol server",
+	F: func(c *cli.CLI, args []string) {
+		err := cli.CheckArgc(args, []int{0, 1})
+		if err != nil {
+			c.User.Put(fmt.Sprintf("%s\n", err))
+			return
+		}
+		addr := gdbDefaultAddr
+		if len(args) == 1 {
+			addr = args[0]
+		}
+		dbg := c.User.(target).GetRiscvDebug()
+		drv := c.User.(memTarget).GetMemoryDriver()
+		tcpAddr, err := net.ResolveTCPAddr("tcp", addr)
+		if err != nil {
+			c.User.Put(fmt.Sprintf("%s\n", err))
+			return
+		}
+		ln, err := net.ListenTCP("tcp", tcpAddr)
+		if err != nil {
+			c.User.Put(fmt.Sprintf("%s\n", err))
+			return
+		}
+		defer ln.Close()
+		// wait for gdb to connect
+		c.User.Put(fmt.Sprintf("waiting for gdb on %s (ctrl-d to abort)\n", ln.Addr()))
+		var conn net.Conn
+		done := c.Loop(func() bool { return gdbAccept(ln, &conn, &err) }, cli.KeycodeCtrlD)
+		if !done {
+			return
+		}
+		if err != nil {
+			c.User.Put(fmt.Sprintf("%s\n", err))
+			return
+		}
+		// gdb expects the harts to be halted
+		s := newGdbServer(dbg, drv, c.User, conn)
+		defer s.close()
+		err = s.haltAll(s.hart)
+		if err != nil {
+			c.User.Put(fmt.Sprintf("unable to halt harts: %v\n", err))
+			return
+		}
+		c.User.Put(fmt.Sprintf("gdb connected from %s (ctrl-d to end session)\n", conn.RemoteAddr()))
+		c.Loop(s.poll, cli.KeycodeCtrlD)
+		if s.err != nil {
+			c.User.Put(fmt.Sprintf("gdb session ended: %v\n", s.err))
+			return
+		}
+		c.User.Put("gdb session ended\n")
+	},
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,249 @@
+//-----------------------------------------------------------------------------
+/*
+
+GDB remote serial protocol test functions.
+
+*/
+//-----------------------------------------------------------------------------
+
+package riscv
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"testing"
+
+	"github.com/deadsy/rvdbg/cpu/riscv/rv"
+)
+
+//-----------------------------------------------------------------------------
+// packet encoding
+
+func Test_GdbChecksum(t *testing.T) {
+	test := []struct {
+		data string
+		sum  uint8
+	}{
+		{"", 0},
+		{"OK", 0x9a},
+		{"qSupported", 0x37},
+		{"\xff\x02", 0x01},
+	}
+	for i, v := range test {
+		sum := gdbChecksum([]byte(v.data))
+		if sum != v.sum {
+			t.Errorf("test %d: expected 0x%02x, actual 0x%02x", i, v.sum, sum)
+		}
+	}
+}
+
+func Test_GdbEscape(t *testing.T) {
+	test := []struct {
+		raw string
+		esc string
+	}{
+		{"", ""},
+		{"OK", "OK"},
+		{"#", "}\x03"},
+		{"$", "}\x04"},
+		{"}", "}]"},
+		{"*", "}\x0a"},
+		{"a#b$c}d*e", "a}\x03b}\x04c}]d}\x0ae"},
+	}
+	for i, v := range test {
+		esc := string(gdbEscape(v.raw))
+		if esc != v.esc {
+			t.Errorf("test %d: escape expected %q, actual %q", i, v.esc, esc)
+		}
+		raw := gdbUnescape([]byte(esc))
+		if raw != v.raw {
+			t.Errorf("test %d: unescape expected %q, actual %q", i, v.raw, raw)
+		}
+	}
+}
+
+// gdbPacket returns a framed packet.
+func gdbPacket(data string) string {
+	return fmt.Sprintf("$%s#%02x", data, gdbChecksum([]byte(data)))
+}
+
+// gdbExchange feeds received data to a gdb server and returns what it sends.
+func gdbExchange(rx string) (string, *gdbServer, error) {
+	a, b := net.Pipe()
+	s := &gdbServer{conn: a, step: -1}
+	go func() {
+		for _, c := range []byte(rx) {
+			s.rxByte(c)
+		}
+		a.Close()
+	}()
+	tx, err := ioutil.ReadAll(b)
+	return string(tx), s, err
+}
+
+func Test_GdbPacket(t *testing.T) {
+	supported := fmt.Sprintf("PacketSize=%x;qXfer:features:read+;QStartNoAckMode+", gdbPacketSize)
+	test := []struct {
+		rx    string
+		tx    string
+		noAck bool
+	}{
+		// acknowledged packets
+		{gdbPacket("qSupported"), "+" + gdbPacket(supported), false},
+		{gdbPacket(""), "+" + gdbPacket(""), false},
+		{gdbPacket("qAttached"), "+" + gdbPacket("1"), false},
+		// acks and junk outside a packet are ignored
+		{"+-x" + gdbPacket("qAttached"), "+" + gdbPacket("1"), false},
+		// bad checksums are rejected
+		{"$qAttached#00", "-", false},
+		{"$qAttached#zz", "-", false},
+		// escaped characters are removed before the packet is handled
+		{"$}Q}\x03#" + fmt.Sprintf("%02x", gdbChecksum([]byte("}Q}\x03"))), "+" + gdbPacket(""), false},
+		// no acknowledgement mode
+		{gdbPacket("QStartNoAckMode") + "$qAttached#00", "+" + gdbPacket("OK") + gdbPacket("1"), true},
+	}
+	for i, v := range test {
+		tx, s, err := gdbExchange(v.rx)
+		if err != nil {
+			t.Errorf("test %d: unexpected error %v", i, err)
+			continue
+		}
+		if tx != v.tx {
+			t.Errorf("test %d: expected %q, actual %q", i, v.tx, tx)
+		}
+		if s.noAck != v.noAck {
+			t.Errorf("test %d: expected noAck %t, actual %t", i, v.noAck, s.noAck)
+		}
+	}
+}
+
+//-----------------------------------------------------------------------------
+// target description
+
+type gdbXMLReg struct {
+	Name    string `xml:"name,attr"`
+	Bitsize uint   `xml:"bitsize,attr"`
+	Regnum  int    `xml:"regnum,attr"`
+}
+
+type gdbXMLFeature struct {
+	Name string      `xml:"name,attr"`
+	Regs []gdbXMLReg `xml:"reg"`
+}
+
+type gdbXMLTarget struct {
+	Arch     string          `xml:"architecture"`
+	Features []gdbXMLFeature `xml:"feature"`
+}
+
+// misaExt returns the misa bits for a set of extensions.
+func misaExt(ext string) uint {
+	x := uint(0)
+	for _, c := range ext {
+		x |= 1 << uint(c-'a')
+	}
+	return x
+}
+
+func Test_GdbTargetXML(t *testing.T) {
+	test := []struct {
+		hi       *rv.HartInfo
+		arch     string
+		features []string        // feature names
+		nregs    map[string]int  // registers per feature
+		present  map[string]uint // register name: bitsize
+		absent   []string        // registers that must not be described
+	}{
+		// rv32 m-mode only, no fpu
+		{
+			&rv.HartInfo{Nregs: 32, MXLEN: 32, DXLEN: 32, MISA: misaExt("imc")},
+			"riscv:rv32",
+			[]string{"org.gnu.gdb.riscv.cpu", "org.gnu.gdb.riscv.csr"},
+			map[string]int{"org.gnu.gdb.riscv.cpu": 33},
+			map[string]uint{"pc": 32, "mstatus": 32, "dpc": 32, "mcycleh": 32},
+			[]string{"ft0", "fflags", "frm", "fcsr", "sstatus", "satp", "ustatus", "vl"},
+		},
+		// rv64 with s/u-modes and double precision floating point
+		{
+			&rv.HartInfo{Nregs: 32, MXLEN: 64, SXLEN: 64, UXLEN: 64, DXLEN: 64, FLEN: 64, MISA: misaExt("imafdcsu")},
+			"riscv:rv64",
+			[]string{"org.gnu.gdb.riscv.cpu", "org.gnu.gdb.riscv.fpu", "org.gnu.gdb.riscv.csr"},
+			map[string]int{"org.gnu.gdb.riscv.cpu": 33, "org.gnu.gdb.riscv.fpu": 35},
+			map[string]uint{"pc": 64, "ft11": 64, "fflags": 32, "mstatus": 64, "sstatus": 64, "satp": 64},
+			[]string{"mcycleh", "vl"},
+		},
+		// rv32e
+		{
+			&rv.HartInfo{Nregs: 16, MXLEN: 32, DXLEN: 32, MISA: misaExt("ec")},
+			"riscv:rv32",
+			[]string{"org.gnu.gdb.riscv.cpu", "org.gnu.gdb.riscv.csr"},
+			map[string]int{"org.gnu.gdb.riscv.cpu": 17},
+			map[string]uint{"pc": 32},
+			[]string{"a6", "ft0"},
+		},
+	}
+	for i, v := range test {
+		v.hi.NewCsr()
+		tgt := gdbXMLTarget{}
+		err := xml.Unmarshal([]byte(gdbTargetXML(v.hi)), &tgt)
+		if err != nil {
+			t.Errorf("test %d: bad xml %v", i, err)
+			continue
+		}
+		if tgt.Arch != v.arch {
+			t.Errorf("test %d: expected architecture %s, actual %s", i, v.arch, tgt.Arch)
+		}
+		if len(tgt.Features) != len(v.features) {
+			t.Errorf("test %d: expected %d features, actual %d", i, len(v.features), len(tgt.Features))
+			continue
+		}
+		regs := map[string]gdbXMLReg{}
+		regnums := map[int]string{}
+		for j, f := range tgt.Features {
+			if f.Name != v.features[j] {
+				t.Errorf("test %d: expected feature %s, actual %s", i, v.features[j], f.Name)
+			}
+			if n, ok := v.nregs[f.Name]; ok && n != len(f.Regs) {
+				t.Errorf("test %d: expected %d registers in %s, actual %d", i, n, f.Name, len(f.Regs))
+			}
+			for _, r := range f.Regs {
+				if x, ok := regnums[r.Regnum]; ok {
+					t.Errorf("test %d: %s and %s have regnum %d", i, x, r.Name, r.Regnum)
+				}
+				regnums[r.Regnum] = r.Name
+				if _, ok := regs[r.Name]; ok {
+					t.Errorf("test %d: %s is described twice", i, r.Name)
+				}
+				regs[r.Name] = r
+				if r.Bitsize == 0 {
+					t.Errorf("test %d: %s has no size", i, r.Name)
+				}
+			}
+		}
+		for name, size := range v.present {
+			r, ok := regs[name]
+			if !ok {
+				t.Errorf("test %d: %s is not described", i, name)
+				continue
+			}
+			if r.Bitsize != size {
+				t.Errorf("test %d: expected %s bitsize %d, actual %d", i, name, size, r.Bitsize)
+			}
+		}
+		for _, name := range v.absent {
+			if _, ok := regs[name]; ok {
+				t.Errorf("test %d: %s should not be described", i, name)
+			}
+		}
+		if r, ok := regs["pc"]; ok && r.Regnum != gdbRegPC {
+			t.Errorf("test %d: expected pc regnum %d, actual %d", i, gdbRegPC, r.Regnum)
+		}
+		if r, ok := regs["mstatus"]; ok && r.Regnum != gdbRegCSR+rv.MSTATUS {
+			t.Errorf("test %d: expected mstatus regnum %d, actual %d", i, gdbRegCSR+rv.MSTATUS, r.Regnum)
+		}
+	}
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,129 @@
+//-----------------------------------------------------------------------------
+/*
+
+GDB Target Description
+
+The target description tells gdb the register set of the hart.
+Register numbers follow the gdb RISC-V numbering:
+
+0..31 x0..x31
+32 pc
+33..64 f0..f31
+65 + n csr n
+
+*/
+//-----------------------------------------------------------------------------
+
+package riscv
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/deadsy/rvdbg/cpu/riscv/rv"
+)
+
+//-----------------------------------------------------------------------------
+
+// gdb register numbers
+const (
+	gdbRegPC  = 32 // program counter
+	gdbRegF0  = 33 // first floating point register
+	gdbRegCSR = 65 // csr 0
+)
+
+// gdbFpuCSR are the csrs in the fpu feature.
+var gdbFpuCSR = []uint{rv.FFLAGS, rv.FRM, rv.FCSR}
+
+var gdbFpuCSRName = map[uint]string{
+	rv.FFLAGS: "fflags",
+	rv.FRM:    "frm",
+	rv.FCSR:   "fcsr",
+}
+
+// gdbXRegType returns the gdb type for an integer register.
+func gdbXRegType(i int) string {
+	switch i {
+	case rv.RegRa:
+		return "code_ptr"
+	case rv.RegSp, rv.RegGp, rv.RegTp, rv.RegS0:
+		return "data_ptr"
+	}
+	return "int"
+}
+
+// gdbReg returns the target description for a register.
+func gdbReg(name string, bits uint, regnum int, typ, group string) string {
+	s := fmt.Sprintf("<reg name=\"%s\" bitsize=\"%d\" regnum=\"%d\" type=\"%s\"", name, bits, regnum, typ)
+	if group != "" {
+		s += fmt.Sprintf(" group=\"%s\"", group)
+	}
+	return s + "/>"
+}
+
+// gdbCSRFeature returns the target description csr feature for a hart.
+func gdbCSRFeature(hi *rv.HartInfo) []string {
+	if hi.CSR == nil {
+		return nil
+	}
+	p, err := hi.CSR.GetPeripheral("CSR")
+	if err != nil {
+		return nil
+	}
+	done := make(map[uint]bool)
+	if hi.FLEN != 0 {
+		for _, csr := range gdbFpuCSR {
+			done[csr] = true
+		}
+	}
+	s := []string{}
+	s = append(s, "<feature name=\"org.gnu.gdb.riscv.csr\">")
+	for _, r := range p.Registers {
+		// register offsets may not be unique
+		if done[r.Offset] || r.Removed() {
+			continue
+		}
+		done[r.Offset] = true
+		// csrs the hart doesn't have (s-mode, fpu, vector) have no size
+		size := rv.GetCSRSize(r.Offset, hi)
+		if size == 0 {
+			continue
+		}
+		s = append(s, gdbReg(r.Name, size, gdbRegCSR+int(r.Offset), "int", "csr"))
+	}
+	return append(s, "</feature>")
+}
+
+// gdbTargetXML returns the gdb target description for a hart.
+func gdbTargetXML(hi *rv.HartInfo) string {
+	s := []string{}
+	s = append(s, "<?xml version=\"1.0\"?>")
+	s = append(s, "<!DOCTYPE target SYSTEM \"gdb-target.dtd\">")
+	s = append(s, "<target version=\"1.0\">")
+	s = append(s, fmt.Sprintf("<architecture>riscv:rv%d</architecture>", hi.MXLEN))
+	// integer registers
+	s = append(s, "<feature name=\"org.gnu.gdb.riscv.cpu\">")
+	for i := 0; i < hi.Nregs; i++ {
+		s = append(s, gdbReg(abiXName[i], hi.MXLEN, i, gdbXRegType(i), ""))
+	}
+	s = append(s, gdbReg("pc", hi.MXLEN, gdbRegPC, "code_ptr", ""))
+	s = append(s, "</feature>")
+	// floating point registers
+	if hi.FLEN != 0 {
+		typ := map[uint]string{32: "ieee_single", 64: "ieee_double"}[hi.FLEN]
+		s = append(s, "<feature name=\"org.gnu.gdb.riscv.fpu\">")
+		for i := range abiFName {
+			s = append(s, gdbReg(abiFName[i], hi.FLEN, gdbRegF0+i, typ, ""))
+		}
+		for _, csr := range gdbFpuCSR {
+			s = append(s, gdbReg(gdbFpuCSRName[csr], 32, gdbRegCSR+int(csr), "int", "float"))
+		}
+		s = append(s, "</feature>")
+	}
+	// control and status registers
+	s = append(s, gdbCSRFeature(hi)...)
+	s = append(s, "</target>")
+	return strings.Join(s, "\n")
+}
+
+//-----------------------------------------------------------------------------
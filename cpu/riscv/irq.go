@@ -0,0 +1,152 @@
+//-----------------------------------------------------------------------------
+/*
+
+RISC-V Interrupt Controller State
+
+Display the live state of the interrupt controller for the target SoC.
+The controller is found by name in the SoC peripherals (ECLIC, PLIC, CLINT)
+and interrupt sources are named using the SoC interrupt table.
+
+*/
+//-----------------------------------------------------------------------------
+
+package riscv
+
+import (
+	"fmt"
+	"strings"
+
+	cli "github.com/deadsy/go-cli"
+	"github.com/deadsy/rvdbg/cpu/riscv/rv"
+	"github.com/deadsy/rvdbg/soc"
+	"github.com/deadsy/rvdbg/util"
+)
+
+//-----------------------------------------------------------------------------
+
+// irqNames returns a map of interrupt names from the SoC interrupt table.
+func irqNames(dev *soc.Device) map[uint]string {
+	names := make(map[uint]string)
+	for _, x := range dev.Interrupts {
+		names[x.IRQ] = x.Name
+	}
+	return names
+}
+
+// maxIRQ returns the largest interrupt number in the SoC interrupt table.
+func maxIRQ(dev *soc.Device) uint {
+	n := uint(0)
+	for _, x := range dev.Interrupts {
+		if x.IRQ > n {
+			n = x.IRQ
+		}
+	}
+	return n
+}
+
+// rdMem32 reads a single 32-bit value from memory.
+func rdMem32(dbg rv.Debug, addr uint) (uint, error) {
+	x, err := dbg.RdMem(32, addr, 1)
+	if err != nil {
+		return 0, err
+	}
+	return x[0], nil
+}
+
+// rdMem64 reads a 64-bit value from memory as 2 x 32-bit reads.
+func rdMem64(dbg rv.Debug, addr uint) (uint, error) {
+	x, err := dbg.RdMem(32, addr, 2)
+	if err != nil {
+		return 0, err
+	}
+	return (x[1] << 32) | x[0], nil
+}
+
+//-----------------------------------------------------------------------------
+// hart interrupt csrs
+
+// mip/mie bit names
+var mieName = map[uint]string{
+	1:  "ssi",
+	3:  "msi",
+	5:  "sti",
+	7:  "mti",
+	9:  "sei",
+	11: "mei",
+}
+
+func mieString(x uint) string {
+	s := []string{}
+	for _, i := range []uint{11, 9, 7, 5, 3, 1} {
+		if x&(1<<i) != 0 {
+			s = append(s, mieName[i])
+		}
+	}
+	if len(s) == 0 {
+		return "none"
+	}
+	return strings.Join(s, ",")
+}
+
+// hartIrqString returns the interrupt related csr state for the current hart.
+func hartIrqString(dbg rv.Debug) string {
+	hi := dbg.GetCurrentHart()
+	state, err := dbg.GetHartState()
+	if err != nil || state != rv.Halted {
+		return fmt.Sprintf("hart%d is not halted, interrupt csrs not read", hi.ID)
+	}
+	csr := []uint{rv.MSTATUS, rv.MIE, rv.MIP}
+	val := make([]uint, len(csr))
+	for i := range csr {
+		x, err := dbg.RdCSR(csr[i], 0)
+		if err != nil {
+			return fmt.Sprintf("unable to read csr 0x%x: %v", csr[i], err)
+		}
+		val[i] = uint(x)
+	}
+	return fmt.Sprintf("hart%d mstatus.mie %d mie %s mip %s", hi.ID, util.Bit(val[0], 3), mieString(val[1]), mieString(val[2]))
+}
+
+//-----------------------------------------------------------------------------
+
+// CmdIrq displays the interrupt controller state.
+var CmdIrq = cli.Leaf{
+	Descr: "display interrupt controller state",
+	F: func(c *cli.CLI, args []string) {
+		dbg := c.User.(target).GetRiscvDebug()
+		dev, _ := c.User.(target).GetSoC()
+		s := []string{}
+		if p, err := dev.GetPeripheral("ECLIC"); err == nil {
+			x, err := eclicString(dbg, dev, p.Addr)
+			if err != nil {
+				c.User.Put(fmt.Sprintf("eclic: %v\n", err))
+				return
+			}
+			s = append(s, x)
+		}
+		if p, err := dev.GetPeripheral("PLIC"); err == nil {
+			x, err := plicString(dbg, dev, p.Addr)
+			if err != nil {
+				c.User.Put(fmt.Sprintf("plic: %v\n", err))
+				return
+			}
+			s = append(s, x)
+		}
+		if p, err := dev.GetPeripheral("CLINT"); err == nil {
+			x, err := clintString(dbg, p.Addr)
+			if err != nil {
+				c.User.Put(fmt.Sprintf("clint: %v\n", err))
+				return
+			}
+			s = append(s, x)
+		}
+		if len(s) == 0 {
+			c.User.Put("no interrupt controller found\n")
+			return
+		}
+		s = append(s, hartIrqString(dbg))
+		c.User.Put(fmt.Sprintf("%s\n", strings.Join(s, "\n\n")))
+	},
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,317 @@
+//-----------------------------------------------------------------------------
+/*
+
+ELF Loader
+
+Load the PT_LOAD segments of an ELF file at their physical addresses.
+Segments in the flash sectors of the target are erased and written with the
+flash driver, other segments are written with the memory driver. A segment
+that straddles flash and ram is split into flash and ram pieces.
+Each segment is read back and verified, a fence.i makes the hart fetch the
+new code, then the pc is set to the entry point.
+
+*/
+//-----------------------------------------------------------------------------
+
+package riscv
+
+import (
+	"bytes"
+	"debug/elf"
+	"fmt"
+	"io"
+	"sort"
+
+	cli "github.com/deadsy/go-cli"
+	"github.com/deadsy/rvdbg/cpu/riscv/rv"
+	"github.com/deadsy/rvdbg/flash"
+	"github.com/deadsy/rvdbg/mem"
+	"github.com/deadsy/rvdbg/util"
+)
+
+//-----------------------------------------------------------------------------
+
+// flashTarget provides a method for getting the flash driver.
+type flashTarget interface {
+	GetFlashDriver() flash.Driver
+}
+
+// elfSegment is a loadable segment of an ELF file.
+type elfSegment struct {
+	index int           // program header index
+	addr  uint          // physical load address
+	data  []byte        // file data
+	flash []*mem.Region // flash sectors for the segment (nil == ram)
+}
+
+// elfSegments returns the loadable segments and entry point of an ELF file.
+func elfSegments(name string) ([]*elfSegment, uint, error) {
+	f, err := elf.Open(name)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer f.Close()
+	if f.Machine != elf.EM_RISCV {
+		return nil, 0, fmt.Errorf("%s is not a RISC-V elf file (%s)", name, f.Machine)
+	}
+	segs := []*elfSegment{}
+	for i, p := range f.Progs {
+		if p.Type != elf.PT_LOAD || p.Filesz == 0 {
+			continue
+		}
+		data := make([]byte, p.Filesz)
+		_, err := io.ReadFull(p.Open(), data)
+		if err != nil {
+			return nil, 0, fmt.Errorf("unable to read segment %d: %v", i, err)
+		}
+		segs = append(segs, &elfSegment{
+			index: i,
+			addr:  uint(p.Paddr),
+			data:  data,
+		})
+	}
+	if len(segs) == 0 {
+		return nil, 0, fmt.Errorf("%s has no loadable segments", name)
+	}
+	return segs, uint(f.Entry), nil
+}
+
+//-----------------------------------------------------------------------------
+// memory access
+
+// wrMemBytes writes a byte buffer to memory, using 32-bit writes where possible.
+func wrMemBytes(drv mem.Driver, addr uint, buf []byte) error {
+	for len(buf) != 0 {
+		if addr&3 != 0 || len(buf) < 4 {
+			err := drv.WrMem(8, addr, []uint{uint(buf[0])})
+			if err != nil {
+				return err
+			}
+			addr++
+			buf = buf[1:]
+			continue
+		}
+		n := len(buf) &^ 3
+		val := make([]uint, n>>2)
+		util.ConvertFromUint8(32, buf[:n], val)
+		err := drv.WrMem(32, addr, val)
+		if err != nil {
+			return err
+		}
+		addr += uint(n)
+		buf = buf[n:]
+	}
+	return nil
+}
+
+// rdMemBytes reads a byte buffer from memory, using 32-bit reads where possible.
+func rdMemBytes(drv mem.Driver, addr, n uint) ([]byte, error) {
+	buf := []byte{}
+	for n != 0 {
+		if addr&3 != 0 || n < 4 {
+			x, err := drv.RdMem(8, addr, 1)
+			if err != nil {
+				return nil, err
+			}
+			buf = append(buf, uint8(x[0]))
+			addr++
+			n--
+			continue
+		}
+		k := n &^ 3
+		x, err := drv.RdMem(32, addr, k>>2)
+		if err != nil {
+			return nil, err
+		}
+		buf = append(buf, util.ConvertToUint8(32, x)...)
+		addr += k
+		n -= k
+	}
+	return buf, nil
+}
+
+// verify reads back a segment and compares it with the file data.
+func (s *elfSegment) verify(drv mem.Driver) error {
+	buf, err := rdMemBytes(drv, s.addr, uint(len(s.data)))
+	if err != nil {
+		return err
+	}
+	for i := range buf {
+		if buf[i] != s.data[i] {
+			return fmt.Errorf("verify failed at 0x%x", s.addr+uint(i))
+		}
+	}
+	return nil
+}
+
+//-----------------------------------------------------------------------------
+// flash
+
+// flashSectors returns the flash sectors overlapping a segment.
+func (s *elfSegment) flashSectors(drv flash.Driver) []*mem.Region {
+	r := mem.NewRegion("", s.addr, uint(len(s.data)), nil)
+	sectors := []*mem.Region{}
+	for _, x := range drv.GetSectors() {
+		if x.Overlaps(r) {
+			sectors = append(sectors, x)
+		}
+	}
+	if len(sectors) == 0 {
+		return nil
+	}
+	return sectors
+}
+
+// split splits a segment into flash and ram pieces.
+func (s *elfSegment) split(drv flash.Driver) []*elfSegment {
+	sectors := s.flashSectors(drv)
+	sort.Slice(sectors, func(i, j int) bool { return sectors[i].Addr < sectors[j].Addr })
+	end := s.addr + uint(len(s.data))
+	segs := []*elfSegment{}
+	add := func(lo, hi uint, sector *mem.Region) {
+		if lo >= hi {
+			return
+		}
+		// extend the previous piece if it is the same kind
+		if n := len(segs); n != 0 {
+			x := segs[n-1]
+			if (x.flash != nil) == (sector != nil) && x.addr+uint(len(x.data)) == lo {
+				x.data = s.data[x.addr-s.addr : hi-s.addr]
+				if sector != nil {
+					x.flash = append(x.flash, sector)
+				}
+				return
+			}
+		}
+		x := &elfSegment{index: s.index, addr: lo, data: s.data[lo-s.addr : hi-s.addr]}
+		if sector != nil {
+			x.flash = []*mem.Region{sector}
+		}
+		segs = append(segs, x)
+	}
+	addr := s.addr
+	for _, x := range sectors {
+		lo := max(x.Addr, addr)
+		hi := min(x.Addr+x.Size, end)
+		add(addr, lo, nil)
+		add(lo, hi, x)
+		addr = max(hi, addr)
+	}
+	add(addr, end, nil)
+	return segs
+}
+
+// wrFlash erases and writes a segment to flash.
+// The erased map records erased sectors so segments sharing a sector don't erase each other.
+func (s *elfSegment) wrFlash(drv flash.Driver, erased map[uint]bool) error {
+	for _, r := range s.flash {
+		if erased[r.Addr] {
+			continue
+		}
+		err := drv.Erase(r)
+		if err != nil {
+			return fmt.Errorf("unable to erase 0x%x: %v", r.Addr, err)
+		}
+		erased[r.Addr] = true
+	}
+	// pad to 32-bit alignment with the erased value
+	r := mem.NewRegion("", s.addr, uint(len(s.data)), nil)
+	r.Align32()
+	buf := bytes.Repeat([]byte{0xff}, int(r.Size))
+	copy(buf[s.addr-r.Addr:], s.data)
+	return drv.Write(r, buf)
+}
+
+//-----------------------------------------------------------------------------
+
+// LoadHelp is help information for the "load" command.
+var LoadHelp = []cli.Help{
+	{"<filename>", "load an elf file and set the pc to the entry point"},
+	{"  filename", "name of file (string)"},
+}
+
+// CmdLoad loads an ELF file into target memory.
+var CmdLoad = cli.Leaf{
+	Descr: "load an elf file",
+	F: func(c *cli.CLI, args []string) {
+		err := cli.CheckArgc(args, []int{1})
+		if err != nil {
+			c.User.Put(fmt.Sprintf("%s\n", err))
+			return
+		}
+		segs, entry, err := elfSegments(args[0])
+		if err != nil {
+			c.User.Put(fmt.Sprintf("%s\n", err))
+			return
+		}
+		dbg := c.User.(target).GetRiscvDebug()
+		hi := dbg.GetCurrentHart()
+		drv := c.User.(memTarget).GetMemoryDriver()
+		var flashDrv flash.Driver
+		if t, ok := c.User.(flashTarget); ok {
+			flashDrv = t.GetFlashDriver()
+		}
+		// split the segments into ram/flash pieces
+		if flashDrv != nil {
+			pieces := []*elfSegment{}
+			for _, s := range segs {
+				pieces = append(pieces, s.split(flashDrv)...)
+			}
+			segs = pieces
+		}
+		err = dbg.HaltHart()
+		if err != nil {
+			c.User.Put(fmt.Sprintf("unable to halt hart%d: %v\n", hi.ID, err))
+			return
+		}
+		// write and verify the segments
+		fmtAddr := util.UintFormat(hi.MXLEN)
+		erased := make(map[uint]bool)
+		rows := [][]string{}
+		nerr := 0
+		for _, s := range segs {
+			where := "ram"
+			if s.flash != nil {
+				where = "flash"
+				err = s.wrFlash(flashDrv, erased)
+			} else {
+				err = wrMemBytes(drv, s.addr, s.data)
+			}
+			if err == nil {
+				err = s.verify(drv)
+			}
+			status := "ok"
+			if err != nil {
+				status = err.Error()
+				nerr++
+			}
+			rows = append(rows, []string{
+				fmt.Sprintf("segment %d", s.index),
+				fmt.Sprintf(fmtAddr+" "+fmtAddr, s.addr, s.addr+uint(len(s.data))-1),
+				util.MemSize(uint(len(s.data))),
+				where,
+				status,
+			})
+		}
+		c.User.Put(fmt.Sprintf("%s\n", cli.TableString(rows, []int{0, 0, 0, 0, 0}, 1)))
+		if nerr != 0 {
+			c.User.Put(fmt.Sprintf("%d segments failed, pc not set\n", nerr))
+			return
+		}
+		// the memory writes bypass the instruction cache
+		err = dbg.FenceI()
+		if err != nil {
+			c.User.Put(fmt.Sprintf("unable to run fence.i: %v\n", err))
+			return
+		}
+		// set the pc to the entry point
+		err = dbg.WrCSR(rv.DPC, 0, uint64(entry))
+		if err != nil {
+			c.User.Put(fmt.Sprintf("unable to set pc: %v\n", err))
+			return
+		}
+		c.User.Put(fmt.Sprintf("pc = %s\n", fmt.Sprintf(fmtAddr, entry)))
+	},
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,166 @@
+//-----------------------------------------------------------------------------
+/*
+
+SiFive Platform-Level Interrupt Controller (PLIC)
+SiFive Core Local Interruptor (CLINT)
+
+PLIC contexts are numbered in hart order with an m-mode context for each
+hart, followed by an s-mode context if the hart has s-mode.
+
+*/
+//-----------------------------------------------------------------------------
+
+package riscv
+
+import (
+	"fmt"
+	"strings"
+
+	cli "github.com/deadsy/go-cli"
+	"github.com/deadsy/rvdbg/cpu/riscv/rv"
+	"github.com/deadsy/rvdbg/soc"
+)
+
+//-----------------------------------------------------------------------------
+
+// PLIC register offsets
+const (
+	plicPriority  = 0x0      // source priorities
+	plicPending   = 0x1000   // pending bits
+	plicEnable    = 0x2000   // enable bits, 0x80 per context
+	plicThreshold = 0x200000 // priority threshold, 0x1000 per context
+)
+
+// plicMaxSources is the maximum number of PLIC interrupt sources.
+const plicMaxSources = 1023
+
+// plicContext is a PLIC interrupt target.
+type plicContext struct {
+	name      string
+	enable    []uint
+	threshold uint
+}
+
+// plicContexts returns the PLIC contexts for the harts.
+func plicContexts(dbg rv.Debug) ([]*plicContext, error) {
+	ctx := []*plicContext{}
+	for i := 0; i < dbg.GetHartCount(); i++ {
+		hi, err := dbg.GetHartInfo(i)
+		if err != nil {
+			return nil, err
+		}
+		ctx = append(ctx, &plicContext{name: fmt.Sprintf("hart%d.m", i)})
+		if hi.SXLEN != 0 {
+			ctx = append(ctx, &plicContext{name: fmt.Sprintf("hart%d.s", i)})
+		}
+	}
+	return ctx, nil
+}
+
+// plicString returns the PLIC state.
+func plicString(dbg rv.Debug, dev *soc.Device, base uint) (string, error) {
+	// sources are 1..n
+	n := maxIRQ(dev)
+	if n == 0 {
+		// no SoC interrupt table, show the raw source numbers
+		n = plicMaxSources
+	}
+	words := (n + 32) >> 5
+	prio, err := dbg.RdMem(32, base+plicPriority+4, n)
+	if err != nil {
+		return "", err
+	}
+	pending, err := dbg.RdMem(32, base+plicPending, words)
+	if err != nil {
+		return "", err
+	}
+	ctx, err := plicContexts(dbg)
+	if err != nil {
+		return "", err
+	}
+	for i, c := range ctx {
+		c.enable, err = dbg.RdMem(32, base+plicEnable+uint(i)*0x80, words)
+		if err != nil {
+			return "", err
+		}
+		c.threshold, err = rdMem32(dbg, base+plicThreshold+uint(i)*0x1000)
+		if err != nil {
+			return "", err
+		}
+	}
+	names := irqNames(dev)
+	s := [][]string{}
+	s = append(s, []string{"irq", "name", "prio", "ip", "enabled", ""})
+	for i := uint(1); i <= n; i++ {
+		ip := (pending[i>>5] >> (i & 31)) & 1
+		en := []string{}
+		notes := []string{}
+		for _, c := range ctx {
+			if (c.enable[i>>5]>>(i&31))&1 != 0 {
+				en = append(en, c.name)
+				if prio[i-1] <= c.threshold {
+					notes = append(notes, fmt.Sprintf("masked by %s threshold", c.name))
+				}
+			}
+		}
+		name, ok := names[i]
+		if !ok && ip == 0 && len(en) == 0 {
+			continue
+		}
+		enStr := "-"
+		if len(en) != 0 {
+			enStr = strings.Join(en, ",")
+		}
+		s = append(s, []string{
+			fmt.Sprintf("%d", i),
+			name,
+			fmt.Sprintf("%d", prio[i-1]),
+			fmt.Sprintf("%d", ip),
+			enStr,
+			strings.Join(notes, ", "),
+		})
+	}
+	thr := []string{}
+	for _, c := range ctx {
+		thr = append(thr, fmt.Sprintf("%s %d", c.name, c.threshold))
+	}
+	hdr := fmt.Sprintf("plic @ 0x%x: %d sources, threshold %s\n", base, n, strings.Join(thr, ", "))
+	return hdr + cli.TableString(s, []int{0, 0, 0, 0, 0, 0}, 1), nil
+}
+
+//-----------------------------------------------------------------------------
+
+// CLINT register offsets
+const (
+	clintMsip     = 0x0    // software interrupt pending, 4 bytes per hart
+	clintMtimecmp = 0x4000 // timer compare, 8 bytes per hart
+	clintMtime    = 0xbff8 // timer
+)
+
+// clintString returns the CLINT state.
+func clintString(dbg rv.Debug, base uint) (string, error) {
+	mtime, err := rdMem64(dbg, base+clintMtime)
+	if err != nil {
+		return "", err
+	}
+	s := []string{}
+	s = append(s, fmt.Sprintf("clint @ 0x%x: mtime 0x%x", base, mtime))
+	for i := 0; i < dbg.GetHartCount(); i++ {
+		msip, err := rdMem32(dbg, base+clintMsip+uint(i)*4)
+		if err != nil {
+			return "", err
+		}
+		mtimecmp, err := rdMem64(dbg, base+clintMtimecmp+uint(i)*8)
+		if err != nil {
+			return "", err
+		}
+		timer := "expired"
+		if mtimecmp > mtime {
+			timer = fmt.Sprintf("expires in %d ticks", mtimecmp-mtime)
+		}
+		s = append(s, fmt.Sprintf("hart%d msip %d mtimecmp 0x%x (%s)", i, msip&1, mtimecmp, timer))
+	}
+	return strings.Join(s, "\n"), nil
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,289 @@
+//-----------------------------------------------------------------------------
+/*
+
+RISC-V Physical Memory Protection
+
+Read the pmpcfg/pmpaddr CSRs and decode the PMP entries into address ranges.
+On RV32 pmpcfgN holds entries 4N..4N+3. On RV64 only the even numbered
+pmpcfgN registers exist and each holds 8 entries.
+
+Unimplemented entries are read-only zero, so the implemented entries are
+found by writing all-ones to pmpaddrN and reading it back. The same probe
+on an entry that is off (or tor) gives the granularity G, the lsb set in
+the read back value.
+
+*/
+//-----------------------------------------------------------------------------
+
+package riscv
+
+import (
+	"fmt"
+	"math/bits"
+	"strings"
+
+	cli "github.com/deadsy/go-cli"
+	"github.com/deadsy/rvdbg/cpu/riscv/rv"
+	"github.com/deadsy/rvdbg/util"
+)
+
+//-----------------------------------------------------------------------------
+
+// maxPMP is the maximum number of PMP entries.
+const maxPMP = 64
+
+// pmpcfg fields
+const (
+	pmpR = (1 << 0) // read
+	pmpW = (1 << 1) // write
+	pmpX = (1 << 2) // execute
+	pmpL = (1 << 7) // locked
+)
+
+// pmpcfg.a address matching modes
+const (
+	pmpOff   = 0 // null region (disabled)
+	pmpTOR   = 1 // top of range
+	pmpNA4   = 2 // naturally aligned four-byte region
+	pmpNAPOT = 3 // naturally aligned power-of-two region, >= 8 bytes
+)
+
+var pmpModeName = [4]string{"off", "tor", "na4", "napot"}
+
+// pmpEntry is a decoded PMP entry.
+type pmpEntry struct {
+	index int      // entry number
+	cfg   uint     // pmpcfg byte
+	addr  uint     // pmpaddr value
+	lo    uint     // first address of the range
+	hi    uint     // last address of the range
+	empty bool     // the range is empty (tor with lo >= hi)
+	notes []string // overlap/lock/reserved notes
+}
+
+func (e *pmpEntry) mode() uint {
+	return util.Bits(e.cfg, 4, 3)
+}
+
+// permString returns the permission string for the entry.
+func (e *pmpEntry) permString() string {
+	s := []byte("----")
+	for i, c := range []byte("rwx") {
+		if e.cfg&(1<<uint(i)) != 0 {
+			s[i] = c
+		}
+	}
+	if e.cfg&pmpL != 0 {
+		s[3] = 'l'
+	}
+	return string(s)
+}
+
+// overlaps returns true if the entry ranges overlap.
+func (e *pmpEntry) overlaps(x *pmpEntry) bool {
+	if e.empty || x.empty {
+		return false
+	}
+	return e.lo <= x.hi && x.lo <= e.hi
+}
+
+//-----------------------------------------------------------------------------
+
+// napotRange returns the first and last address for a NAPOT pmpaddr value.
+func napotRange(addr, xlen uint) (uint, uint) {
+	// count the trailing ones
+	t := uint(0)
+	for t < xlen && addr&(1<<t) != 0 {
+		t++
+	}
+	if t >= xlen || t+3 >= 64 {
+		// the whole address space
+		return 0, util.Mask(xlen+1, 0)
+	}
+	size := uint(1) << (t + 3)
+	lo := (addr &^ ((1 << t) - 1)) << 2
+	return lo, lo + size - 1
+}
+
+// pmpDecode decodes the PMP entries for the pmpcfg bytes and pmpaddr values.
+// gran is the PMP granularity in bytes (0 == unknown).
+func pmpDecode(cfg []uint8, addr []uint, xlen, gran uint) []*pmpEntry {
+	entries := []*pmpEntry{}
+	for i := range addr {
+		e := &pmpEntry{
+			index: i,
+			cfg:   uint(cfg[i]),
+			addr:  addr[i],
+		}
+		switch e.mode() {
+		case pmpOff:
+			continue
+		case pmpTOR:
+			if i != 0 {
+				e.lo = addr[i-1] << 2
+			}
+			if e.addr<<2 <= e.lo {
+				e.empty = true
+				e.notes = append(e.notes, "empty range")
+			} else {
+				e.hi = (e.addr << 2) - 1
+			}
+		case pmpNA4:
+			e.lo = e.addr << 2
+			e.hi = e.lo + 3
+			if gran > 4 {
+				e.notes = append(e.notes, fmt.Sprintf("na4 with %d byte granularity", gran))
+			}
+		case pmpNAPOT:
+			e.lo, e.hi = napotRange(e.addr, xlen)
+		}
+		if e.cfg&(pmpR|pmpW) == pmpW {
+			e.notes = append(e.notes, "reserved w without r")
+		}
+		if e.cfg&pmpL != 0 {
+			e.notes = append(e.notes, "locked, applies to m-mode")
+		}
+		// lower numbered entries have priority
+		for _, x := range entries {
+			if x.overlaps(e) {
+				e.notes = append(e.notes, fmt.Sprintf("overlaps pmp%d", x.index))
+			}
+		}
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+//-----------------------------------------------------------------------------
+
+// pmpProbe writes all-ones to a pmpaddr register and returns the value read back.
+// The pmpaddr register is restored.
+func pmpProbe(dbg rv.Debug, i int) (uint, error) {
+	reg := rv.PMPADDR0 + uint(i)
+	old, err := dbg.RdCSR(reg, 0)
+	if err != nil {
+		return 0, err
+	}
+	err = dbg.WrCSR(reg, 0, ^uint64(0))
+	if err != nil {
+		return 0, err
+	}
+	x, err := dbg.RdCSR(reg, 0)
+	if err != nil {
+		return 0, err
+	}
+	return uint(x), dbg.WrCSR(reg, 0, old)
+}
+
+// pmpLocked returns true if writes to pmpaddr[i] are ignored.
+// This is the case for a locked entry or an entry below a locked TOR entry.
+func pmpLocked(cfg []uint8, i int) bool {
+	if cfg[i]&pmpL != 0 {
+		return true
+	}
+	if i+1 < len(cfg) {
+		x := cfg[i+1]
+		return x&pmpL != 0 && util.Bits(uint(x), 4, 3) == pmpTOR
+	}
+	return false
+}
+
+// rdPMP reads the pmpcfg bytes and pmpaddr values for the implemented PMP entries.
+// It also returns the PMP granularity in bytes (0 == unknown).
+func rdPMP(dbg rv.Debug, xlen uint) ([]uint8, []uint, uint, error) {
+	// read the pmpcfg registers
+	cfg := []uint8{}
+	perReg := int(xlen >> 3)
+	for len(cfg) < maxPMP {
+		reg := rv.PMPCFG0 + uint(len(cfg)>>2)
+		x, err := dbg.RdCSR(reg, 0)
+		if err != nil {
+			if len(cfg) == 0 {
+				return nil, nil, 0, fmt.Errorf("unable to read pmpcfg0: %v", err)
+			}
+			break
+		}
+		for i := 0; i < perReg; i++ {
+			cfg = append(cfg, uint8(x>>(8*uint(i))))
+		}
+	}
+	// read the pmpaddr registers of the implemented entries
+	addr := []uint{}
+	gran := uint(0)
+	for i := range cfg {
+		x, err := dbg.RdCSR(rv.PMPADDR0+uint(i), 0)
+		if err != nil {
+			break
+		}
+		// Locked entries ignore writes, but are implemented. The address of
+		// an entry below a locked TOR entry is also locked.
+		if !pmpLocked(cfg, i) {
+			ones, err := pmpProbe(dbg, i)
+			if err != nil {
+				return nil, nil, 0, fmt.Errorf("unable to probe pmpaddr%d: %v", i, err)
+			}
+			if ones == 0 {
+				// entries are implemented lowest numbered first
+				break
+			}
+			mode := util.Bits(uint(cfg[i]), 4, 3)
+			if gran == 0 && (mode == pmpOff || mode == pmpTOR) {
+				gran = 4 << uint(bits.TrailingZeros64(uint64(ones)))
+			}
+		}
+		addr = append(addr, uint(x))
+	}
+	return cfg, addr, gran, nil
+}
+
+// pmpString returns the display string for the PMP entries.
+func pmpString(entries []*pmpEntry, xlen uint) string {
+	if len(entries) == 0 {
+		return "no pmp entries are enabled"
+	}
+	fmtx := util.UintFormat(xlen)
+	s := [][]string{}
+	for _, e := range entries {
+		rangeStr := "-"
+		if !e.empty {
+			rangeStr = fmt.Sprintf(fmtx+" "+fmtx, e.lo, e.hi)
+		}
+		s = append(s, []string{
+			fmt.Sprintf("pmp%d", e.index),
+			pmpModeName[e.mode()],
+			rangeStr,
+			e.permString(),
+			strings.Join(e.notes, ", "),
+		})
+	}
+	return cli.TableString(s, []int{0, 0, 0, 0, 0}, 1)
+}
+
+//-----------------------------------------------------------------------------
+
+// CmdPMP displays the physical memory protection entries.
+var CmdPMP = cli.Leaf{
+	Descr: "display physical memory protection entries",
+	F: func(c *cli.CLI, args []string) {
+		dbg := c.User.(target).GetRiscvDebug()
+		hi := dbg.GetCurrentHart()
+		err := dbg.HaltHart()
+		if err != nil {
+			c.User.Put(fmt.Sprintf("unable to halt hart%d: %v\n", hi.ID, err))
+			return
+		}
+		cfg, addr, gran, err := rdPMP(dbg, hi.MXLEN)
+		if err != nil {
+			c.User.Put(fmt.Sprintf("%s\n", err))
+			return
+		}
+		granStr := "unknown"
+		if gran != 0 {
+			granStr = fmt.Sprintf("%d bytes", gran)
+		}
+		c.User.Put(fmt.Sprintf("%d pmp entries, granularity %s\n", len(addr), granStr))
+		c.User.Put(fmt.Sprintf("%s\n", pmpString(pmpDecode(cfg, addr, hi.MXLEN, gran), hi.MXLEN)))
+	},
+}
+
+//-----------------------------------------------------------------------------
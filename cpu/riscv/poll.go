@@ -0,0 +1,200 @@
+//-----------------------------------------------------------------------------
+/*
+
+RISC-V Halt Poller
+
+The poller runs in the background and reports harts that halt on their own
+(breakpoints, ebreaks, steps, reset halts). The debug interface isn't safe
+for concurrent use, so the CLI commands are wrapped to hold the poller lock
+while they run.
+
+*/
+//-----------------------------------------------------------------------------
+
+package riscv
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	cli "github.com/deadsy/go-cli"
+	"github.com/deadsy/rvdbg/cpu/riscv/rv"
+)
+
+//-----------------------------------------------------------------------------
+
+const pollInterval = 250 * time.Millisecond
+
+// Poller polls the harts for asynchronous halts.
+type Poller struct {
+	mu     sync.Mutex
+	wg     sync.WaitGroup
+	dbg    rv.Debug
+	user   cli.USER
+	prompt func() string // returns the current prompt (called holding the lock)
+	stop   chan struct{}
+}
+
+// NewPoller returns a halt poller.
+func NewPoller(dbg rv.Debug, user cli.USER, prompt func() string) *Poller {
+	return &Poller{
+		dbg:    dbg,
+		user:   user,
+		prompt: prompt,
+	}
+}
+
+// GetPrompt returns the current prompt.
+// The prompt shows the hart state, which the poller updates.
+func (p *Poller) GetPrompt() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.prompt()
+}
+
+// put outputs an asynchronous message. The partial input line of the line
+// editor is erased and the prompt is redrawn after the message.
+// It is called holding the lock.
+func (p *Poller) put(msg string) {
+	p.user.Put(fmt.Sprintf("\r\x1b[K%s\n%s", msg, p.prompt()))
+}
+
+// Start starts the poller.
+func (p *Poller) Start() {
+	p.stop = make(chan struct{})
+	p.wg.Add(1)
+	go p.run()
+}
+
+// Stop stops the poller.
+func (p *Poller) Stop() {
+	close(p.stop)
+	p.wg.Wait()
+}
+
+func (p *Poller) run() {
+	defer p.wg.Done()
+	delay := pollInterval
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-time.After(delay):
+			serviced, err := p.poll()
+			if err != nil {
+				p.mu.Lock()
+				p.put(fmt.Sprintf("halt poller stopped: %v", err))
+				p.mu.Unlock()
+				return
+			}
+			// poll faster while the harts are making semihosting calls
+			delay = pollInterval
+			if serviced {
+				delay = semihostInterval
+			}
+		}
+	}
+}
+
+// poll checks the running harts and reports any that have halted.
+// It returns true if a semihosting call was serviced.
+func (p *Poller) poll() (bool, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	dbg := p.dbg
+	id := dbg.GetCurrentHart().ID
+	s := []string{}
+	serviced := false
+	for i := 0; i < dbg.GetHartCount(); i++ {
+		hi, err := dbg.SetCurrentHart(i)
+		if err != nil {
+			return false, err
+		}
+		if hi.State != rv.Running {
+			continue
+		}
+		state, err := dbg.GetHartState()
+		if err != nil {
+			return false, err
+		}
+		if state == rv.Halted {
+			if semihosting.enabled {
+				resumed, msg, err := semihosting.service(dbg, p.user)
+				if err != nil {
+					return false, err
+				}
+				if resumed {
+					serviced = true
+					continue
+				}
+				if msg != "" {
+					s = append(s, msg)
+				}
+			}
+			x, err := haltReport(dbg, p.user.(target).GetSymbols())
+			if err != nil {
+				return false, err
+			}
+			s = append(s, x)
+		}
+	}
+	_, err := dbg.SetCurrentHart(id)
+	if err != nil {
+		return false, err
+	}
+	if len(s) != 0 {
+		p.put(strings.Join(s, "\n"))
+	}
+	return serviced, nil
+}
+
+//-----------------------------------------------------------------------------
+
+// haltReport returns the halt cause and pc for the current hart.
+func haltReport(dbg rv.Debug, syms *Symbols) (string, error) {
+	s, err := haltString(dbg, syms)
+	if err != nil {
+		return "", err
+	}
+	if s == "" {
+		s = fmt.Sprintf("hart%d halted: %s", dbg.GetCurrentHart().ID, rv.CauseHaltReq)
+	}
+	pc, err := pcString(dbg, syms)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s\n%s", s, pc), nil
+}
+
+//-----------------------------------------------------------------------------
+
+// lockLeaf returns a leaf function that runs holding the poller lock.
+func (p *Poller) lockLeaf(leaf cli.Leaf) cli.Leaf {
+	f := leaf.F
+	leaf.F = func(c *cli.CLI, args []string) {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		f(c, args)
+	}
+	return leaf
+}
+
+// Menu returns a copy of a menu with the leaf functions wrapped to hold the poller lock.
+func (p *Poller) Menu(m cli.Menu) cli.Menu {
+	menu := make(cli.Menu, len(m))
+	for i := range m {
+		item := append(cli.MenuItem{}, m[i]...)
+		switch x := item[1].(type) {
+		case cli.Leaf:
+			item[1] = p.lockLeaf(x)
+		case cli.Menu:
+			item[1] = p.Menu(x)
+		}
+		menu[i] = item
+	}
+	return menu
+}
+
+//-----------------------------------------------------------------------------
@@ -32,7 +32,8 @@ const drDtmLength = 32
 //-----------------------------------------------------------------------------
 
 // NewDebug returns a new RISC-V debugger interface.
-func NewDebug(dev *jtag.Device) (rv.Debug, error) {
+// The authenticator is used for locked 0.13 debug modules (nil == no authentication).
+func NewDebug(dev *jtag.Device, auth rv13.Authenticator) (rv.Debug, error) {
 
 	// check the IR length
 	if dev.GetIRLength() != irLength {
@@ -60,7 +61,7 @@ func NewDebug(dev *jtag.Device) (rv.Debug, error) {
 	case 0:
 		return rv11.New(dev)
 	case 1:
-		return rv13.New(dev)
+		return rv13.New(dev, auth)
 	}
 
 	return nil, fmt.Errorf("unknown dtm version %d", version)
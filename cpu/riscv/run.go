@@ -0,0 +1,617 @@
+//-----------------------------------------------------------------------------
+/*
+
+RISC-V Run Control Commands
+
+*/
+//-----------------------------------------------------------------------------
+
+package riscv
+
+import (
+	"fmt"
+	"time"
+
+	cli "github.com/deadsy/go-cli"
+	"github.com/deadsy/rvdbg/cpu/riscv/rv"
+)
+
+//-----------------------------------------------------------------------------
+
+// pcString returns the current pc as a disassembly string.
+func pcString(dbg rv.Debug, syms *Symbols) (string, error) {
+	pc, err := dbg.RdCSR(rv.DPC, 0)
+	if err != nil {
+		return "", fmt.Errorf("unable to read pc: %v", err)
+	}
+	da, err := disassemble(dbg, syms, uint(pc))
+	if err != nil {
+		return "", err
+	}
+	return da.String(), nil
+}
+
+//-----------------------------------------------------------------------------
+// reset
+
+// saveBreakpoints takes the breakpoints off all harts before a reset.
+// It returns true if any hart has breakpoints to set again.
+func saveBreakpoints(dbg rv.Debug) ([][]*rv.Breakpoint, bool, error) {
+	id := dbg.GetCurrentHart().ID
+	defer dbg.SetCurrentHart(id)
+	bps := make([][]*rv.Breakpoint, dbg.GetHartCount())
+	armed := false
+	for i := range bps {
+		hi, err := dbg.SetCurrentHart(i)
+		if err != nil {
+			return nil, false, err
+		}
+		soft := false
+		armed = armed || len(hi.Breakpoints) != 0
+		for _, bp := range hi.Breakpoints {
+			soft = soft || (bp.Type == rv.BreakSoftware && bp.Enabled)
+		}
+		save := func() error {
+			var err error
+			bps[i], err = rv.SaveBreakpoints(dbg)
+			return err
+		}
+		if soft {
+			// memory writes need a halted hart
+			err = haltedDo(dbg, save)
+		} else {
+			err = save()
+		}
+		if err != nil {
+			return nil, false, fmt.Errorf("hart%d: %v", i, err)
+		}
+	}
+	return bps, armed, nil
+}
+
+// restoreBreakpoints sets the saved breakpoints on all (halted) harts after a reset.
+func restoreBreakpoints(dbg rv.Debug, bps [][]*rv.Breakpoint) error {
+	id := dbg.GetCurrentHart().ID
+	defer dbg.SetCurrentHart(id)
+	for i := range bps {
+		if len(bps[i]) == 0 {
+			continue
+		}
+		_, err := dbg.SetCurrentHart(i)
+		if err != nil {
+			return err
+		}
+		err = rv.RestoreBreakpoints(dbg, bps[i])
+		if err != nil {
+			return fmt.Errorf("hart%d: %v", i, err)
+		}
+	}
+	return nil
+}
+
+// ResetHelp is help for the reset command.
+var ResetHelp = []cli.Help{
+	{"[halt|run]", "halt or run the harts after reset, default is run"},
+}
+
+// CmdReset resets the system.
+var CmdReset = cli.Leaf{
+	Descr: "reset the system",
+	F: func(c *cli.CLI, args []string) {
+		dbg := c.User.(target).GetRiscvDebug()
+		err := cli.CheckArgc(args, []int{0, 1})
+		if err != nil {
+			c.User.Put(fmt.Sprintf("%s\n", err))
+			return
+		}
+		halt := false
+		if len(args) == 1 {
+			switch args[0] {
+			case "halt":
+				halt = true
+			case "run":
+				halt = false
+			default:
+				c.User.Put(fmt.Sprintf("unknown reset mode \"%s\"\n", args[0]))
+				return
+			}
+		}
+		bps, armed, err := saveBreakpoints(dbg)
+		if err != nil {
+			c.User.Put(fmt.Sprintf("unable to remove breakpoints: %v\n", err))
+			return
+		}
+		// The reset clears the triggers and the dcsr ebreak bits. If they are
+		// needed from the first instruction reset halted, set them and then run.
+		armed = armed || semihosting.enabled
+		err = dbg.Reset(halt || armed)
+		if err != nil {
+			c.User.Put(fmt.Sprintf("unable to reset: %v\n", err))
+			return
+		}
+		err = restoreBreakpoints(dbg, bps)
+		if err != nil {
+			c.User.Put(fmt.Sprintf("unable to set breakpoints: %v\n", err))
+		}
+		if semihosting.enabled {
+			semihosting.waiting = make(map[int]bool)
+			err = setSemihosting(dbg, true)
+			if err != nil {
+				c.User.Put(fmt.Sprintf("unable to set dcsr ebreak bits, semihosting is off: %v\n", err))
+				semihosting.enable(false)
+			}
+		}
+		if armed && !halt {
+			_, err = dbg.ResumeAll()
+			if err != nil {
+				c.User.Put(fmt.Sprintf("unable to resume harts: %v\n", err))
+			}
+			return
+		}
+		hi := dbg.GetCurrentHart()
+		if hi.State != rv.Halted {
+			return
+		}
+		s, err := pcString(dbg, c.User.(target).GetSymbols())
+		if err != nil {
+			c.User.Put(fmt.Sprintf("%s\n", err))
+			return
+		}
+		c.User.Put(fmt.Sprintf("%s\n", s))
+	},
+}
+
+//-----------------------------------------------------------------------------
+// single step
+
+// StepHelp is help for the step commands.
+var StepHelp = []cli.Help{
+	{"[n] [ie]", "single step n instructions, interrupts are disabled"},
+	{"  n", "number of instructions (decimal), default is 1"},
+	{"  ie", "enable interrupts while stepping (dcsr.stepie)"},
+}
+
+// stepArgs converts step arguments to an (n, interrupts enabled) tuple.
+func stepArgs(args []string) (uint, bool, error) {
+	err := cli.CheckArgc(args, []int{0, 1, 2})
+	if err != nil {
+		return 0, false, err
+	}
+	n := uint(1)
+	ie := false
+	count := false
+	for _, arg := range args {
+		if arg == "ie" && !ie {
+			ie = true
+			continue
+		}
+		if count {
+			return 0, false, fmt.Errorf("unknown argument \"%s\"", arg)
+		}
+		n, err = cli.UintArg(arg, [2]uint{1, 0xffffffff}, 10)
+		if err != nil {
+			return 0, false, err
+		}
+		count = true
+	}
+	return n, ie, nil
+}
+
+// step single steps the current hart n times.
+func step(c *cli.CLI, args []string) {
+	dbg := c.User.(target).GetRiscvDebug()
+	hi := dbg.GetCurrentHart()
+	n, ie, err := stepArgs(args)
+	if err != nil {
+		c.User.Put(fmt.Sprintf("%s\n", err))
+		return
+	}
+	err = dbg.HaltHart()
+	if err != nil {
+		c.User.Put(fmt.Sprintf("unable to halt hart%d: %v\n", hi.ID, err))
+		return
+	}
+	for i := uint(0); i < n; i++ {
+		err := rv.Step(dbg, ie)
+		if err != nil {
+			c.User.Put(fmt.Sprintf("unable to step hart%d: %v\n", hi.ID, err))
+			return
+		}
+		// did we halt for some other reason?
+		cause, err := rv.GetHaltCause(dbg)
+		if err != nil {
+			c.User.Put(fmt.Sprintf("unable to read halt cause: %v\n", err))
+			return
+		}
+		if cause != rv.CauseStep {
+			s, err := haltString(dbg, c.User.(target).GetSymbols())
+			if err != nil {
+				c.User.Put(fmt.Sprintf("unable to read halt cause: %v\n", err))
+				return
+			}
+			c.User.Put(fmt.Sprintf("%s\n", s))
+			break
+		}
+	}
+	s, err := pcString(dbg, c.User.(target).GetSymbols())
+	if err != nil {
+		c.User.Put(fmt.Sprintf("%s\n", err))
+		return
+	}
+	c.User.Put(fmt.Sprintf("%s\n", s))
+}
+
+// CmdStep single steps the current hart.
+var CmdStep = cli.Leaf{
+	Descr: "single step instructions",
+	F: func(c *cli.CLI, args []string) {
+		step(c, args)
+	},
+}
+
+// CmdStepi single steps the current hart (same as step).
+var CmdStepi = cli.Leaf{
+	Descr: "single step instructions",
+	F: func(c *cli.CLI, args []string) {
+		step(c, args)
+	},
+}
+
+//-----------------------------------------------------------------------------
+// continue
+
+// waitHalt is called repeatedly until the current hart halts.
+// Semihosting calls are serviced and the hart is resumed.
+func waitHalt(dbg rv.Debug, user cli.USER, err *error) bool {
+	state, e := dbg.GetHartState()
+	if e != nil {
+		*err = e
+		return true
+	}
+	if state == rv.Halted {
+		if !semihosting.enabled {
+			return true
+		}
+		resumed, msg, e := semihosting.service(dbg, user)
+		if e != nil {
+			*err = e
+			return true
+		}
+		if resumed {
+			return false
+		}
+		if msg != "" {
+			user.Put(msg + "\n")
+		}
+		return true
+	}
+	time.Sleep(10 * time.Millisecond)
+	return false
+}
+
+// CmdContinue resumes the current hart and waits for it to halt.
+var CmdContinue = cli.Leaf{
+	Descr: "resume the current hart and wait for a halt",
+	F: func(c *cli.CLI, args []string) {
+		dbg := c.User.(target).GetRiscvDebug()
+		hi := dbg.GetCurrentHart()
+		if hi.State != rv.Running {
+			err := rv.StepOverBreakpoint(dbg)
+			if err != nil {
+				c.User.Put(fmt.Sprintf("hart%d: %v\n", hi.ID, err))
+				return
+			}
+			err = dbg.ResumeHart()
+			if err != nil {
+				c.User.Put(fmt.Sprintf("unable to resume hart%d: %v\n", hi.ID, err))
+				return
+			}
+		}
+		c.User.Put("running (ctrl-d to abort)\n")
+		var err error
+		done := c.Loop(func() bool { return waitHalt(dbg, c.User, &err) }, cli.KeycodeCtrlD)
+		if err != nil {
+			c.User.Put(fmt.Sprintf("hart%d: %v\n", hi.ID, err))
+			return
+		}
+		if !done {
+			err := dbg.HaltHart()
+			if err != nil {
+				c.User.Put(fmt.Sprintf("unable to halt hart%d: %v\n", hi.ID, err))
+				return
+			}
+		}
+		s, err := haltReport(dbg, c.User.(target).GetSymbols())
+		if err != nil {
+			c.User.Put(fmt.Sprintf("%s\n", err))
+			return
+		}
+		c.User.Put(fmt.Sprintf("%s\n", s))
+	},
+}
+
+//-----------------------------------------------------------------------------
+// run to address
+
+// runState is the state for running a hart until it reaches an address.
+type runState struct {
+	dbg  rv.Debug
+	user cli.USER
+	addr uint   // target address
+	sp   uint64 // stop when the stack pointer is >= this value (recursion)
+	step bool   // no free triggers, single step to the address
+	err  error
+}
+
+// atTarget returns true if the hart has reached the target address and stack depth.
+func (rs *runState) atTarget() (bool, error) {
+	pc, err := rs.dbg.RdCSR(rv.DPC, 0)
+	if err != nil {
+		return false, err
+	}
+	if uint(pc) != rs.addr {
+		return false, nil
+	}
+	sp, err := rs.dbg.RdGPR(rv.RegSp, 0)
+	if err != nil {
+		return false, err
+	}
+	return sp >= rs.sp, nil
+}
+
+// runLoop is called repeatedly until the hart reaches the target address.
+func runLoop(rs *runState) bool {
+	dbg := rs.dbg
+	if rs.step {
+		rs.err = rv.Step(dbg, false)
+		if rs.err != nil {
+			return true
+		}
+		cause, err := rv.GetHaltCause(dbg)
+		if err != nil {
+			rs.err = err
+			return true
+		}
+		if cause == rv.CauseEbreak && semihosting.enabled {
+			// service the call and keep stepping
+			ok, msg, err := semihosting.call(dbg, rs.user)
+			if err != nil {
+				rs.err = err
+				return true
+			}
+			if !ok {
+				if msg != "" {
+					rs.user.Put(msg + "\n")
+				}
+				return true
+			}
+		} else if cause != rv.CauseStep {
+			// halted for some other reason
+			return true
+		}
+		done, err := rs.atTarget()
+		if err != nil {
+			rs.err = err
+			return true
+		}
+		return done
+	}
+	state, err := dbg.GetHartState()
+	if err != nil {
+		rs.err = err
+		return true
+	}
+	if state != rv.Halted {
+		time.Sleep(10 * time.Millisecond)
+		return false
+	}
+	if semihosting.enabled {
+		resumed, msg, err := semihosting.service(dbg, rs.user)
+		if err != nil {
+			rs.err = err
+			return true
+		}
+		if resumed {
+			return false
+		}
+		if msg != "" {
+			rs.user.Put(msg + "\n")
+			return true
+		}
+	}
+	done, err := rs.atTarget()
+	if err != nil {
+		rs.err = err
+		return true
+	}
+	if done {
+		return true
+	}
+	// Did we halt at the target address in a deeper stack frame?
+	pc, err := dbg.RdCSR(rv.DPC, 0)
+	if err != nil {
+		rs.err = err
+		return true
+	}
+	if uint(pc) != rs.addr {
+		// halted for some other reason
+		return true
+	}
+	// keep going
+	rs.err = rv.StepOverBreakpoint(dbg)
+	if rs.err != nil {
+		return true
+	}
+	rs.err = dbg.ResumeHart()
+	return rs.err != nil
+}
+
+// runTo runs the current hart until it reaches an address with a stack pointer >= sp.
+// A temporary hardware breakpoint is used. If there are no free triggers we single step.
+// A disabled breakpoint at the address is enabled for the run.
+func runTo(c *cli.CLI, dbg rv.Debug, addr uint, sp uint64) error {
+	hi := dbg.GetCurrentHart()
+	rs := &runState{
+		dbg:  dbg,
+		user: c.User,
+		addr: addr,
+		sp:   sp,
+	}
+	// is there already a breakpoint at the address?
+	var tmp, disabled *rv.Breakpoint
+	exists := false
+	for _, bp := range hi.Breakpoints {
+		if bp.Addr == addr {
+			exists = true
+			if !bp.Enabled {
+				disabled = bp
+			}
+		}
+	}
+	if disabled != nil {
+		err := rv.EnableBreakpoint(dbg, disabled.ID, true)
+		if err != nil {
+			return err
+		}
+	}
+	// cleanup puts the breakpoints back the way they were
+	cleanup := func() error {
+		if tmp != nil {
+			return rv.RemoveBreakpoint(dbg, tmp.ID)
+		}
+		if disabled != nil {
+			return rv.EnableBreakpoint(dbg, disabled.ID, false)
+		}
+		return nil
+	}
+	if !exists {
+		var err error
+		tmp, err = rv.AddBreakpoint(dbg, addr, rv.BreakHardware)
+		if err == rv.ErrNoTriggers || err == rv.ErrNoFreeTriggers {
+			rs.step = true
+		} else if err != nil {
+			return err
+		}
+	}
+	if !rs.step {
+		err := rv.StepOverBreakpoint(dbg)
+		if err == nil {
+			err = dbg.ResumeHart()
+		}
+		if err != nil {
+			cleanup()
+			return err
+		}
+	}
+	c.User.Put("running (ctrl-d to abort)\n")
+	done := c.Loop(func() bool { return runLoop(rs) }, cli.KeycodeCtrlD)
+	if !done {
+		err := dbg.HaltHart()
+		if err != nil {
+			return err
+		}
+	}
+	err := cleanup()
+	if err != nil {
+		return err
+	}
+	return rs.err
+}
+
+// runReport reports the hart state after a run.
+func runReport(c *cli.CLI, dbg rv.Debug, addr uint) {
+	syms := c.User.(target).GetSymbols()
+	s, err := haltString(dbg, syms)
+	if err != nil {
+		c.User.Put(fmt.Sprintf("unable to read halt cause: %v\n", err))
+		return
+	}
+	pc, err := dbg.RdCSR(rv.DPC, 0)
+	if err != nil {
+		c.User.Put(fmt.Sprintf("unable to read pc: %v\n", err))
+		return
+	}
+	// don't report our own temporary breakpoint
+	if s != "" && uint(pc) != addr {
+		c.User.Put(fmt.Sprintf("%s\n", s))
+	}
+	s, err = pcString(dbg, syms)
+	if err != nil {
+		c.User.Put(fmt.Sprintf("%s\n", err))
+		return
+	}
+	c.User.Put(fmt.Sprintf("%s\n", s))
+}
+
+// CmdNext steps over calls.
+var CmdNext = cli.Leaf{
+	Descr: "single step, stepping over calls",
+	F: func(c *cli.CLI, args []string) {
+		dbg := c.User.(target).GetRiscvDebug()
+		hi := dbg.GetCurrentHart()
+		err := dbg.HaltHart()
+		if err != nil {
+			c.User.Put(fmt.Sprintf("unable to halt hart%d: %v\n", hi.ID, err))
+			return
+		}
+		pc, err := dbg.RdCSR(rv.DPC, 0)
+		if err != nil {
+			c.User.Put(fmt.Sprintf("unable to read pc: %v\n", err))
+			return
+		}
+		ins, err := dbg.RdMem(16, uint(pc), 2)
+		if err != nil {
+			c.User.Put(fmt.Sprintf("unable to read memory at %x\n", pc))
+			return
+		}
+		x := (ins[1] << 16) | ins[0]
+		if !rv.InsIsCall(x, hi.MXLEN) {
+			step(c, nil)
+			return
+		}
+		// run to the return address
+		da := hi.ISA.Disassemble(uint(pc), x)
+		addr := uint(pc) + da.InsLength
+		sp, err := dbg.RdGPR(rv.RegSp, 0)
+		if err != nil {
+			c.User.Put(fmt.Sprintf("unable to read sp: %v\n", err))
+			return
+		}
+		err = runTo(c, dbg, addr, sp)
+		if err != nil {
+			c.User.Put(fmt.Sprintf("hart%d: %v\n", hi.ID, err))
+		}
+		runReport(c, dbg, addr)
+	},
+}
+
+// CmdFinish runs until the current function returns.
+var CmdFinish = cli.Leaf{
+	Descr: "run until the current function returns",
+	F: func(c *cli.CLI, args []string) {
+		dbg := c.User.(target).GetRiscvDebug()
+		hi := dbg.GetCurrentHart()
+		err := dbg.HaltHart()
+		if err != nil {
+			c.User.Put(fmt.Sprintf("unable to halt hart%d: %v\n", hi.ID, err))
+			return
+		}
+		ra, err := dbg.RdGPR(rv.RegRa, 0)
+		if err != nil {
+			c.User.Put(fmt.Sprintf("unable to read ra: %v\n", err))
+			return
+		}
+		sp, err := dbg.RdGPR(rv.RegSp, 0)
+		if err != nil {
+			c.User.Put(fmt.Sprintf("unable to read sp: %v\n", err))
+			return
+		}
+		err = runTo(c, dbg, uint(ra), sp)
+		if err != nil {
+			c.User.Put(fmt.Sprintf("hart%d: %v\n", hi.ID, err))
+		}
+		runReport(c, dbg, uint(ra))
+	},
+}
+
+//-----------------------------------------------------------------------------
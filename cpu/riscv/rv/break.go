@@ -0,0 +1,437 @@
+//-----------------------------------------------------------------------------
+/*
+
+RISC-V Breakpoints
+
+Hardware breakpoints use an execute trigger from the trigger module.
+Software breakpoints patch an ebreak (or c.ebreak) over the instruction at
+the breakpoint address. They only work for code running from RAM.
+The debugger writes memory around the instruction cache, so a fence.i is run
+after each patch.
+
+*/
+//-----------------------------------------------------------------------------
+
+package rv
+
+import (
+	"fmt"
+
+	cli "github.com/deadsy/go-cli"
+	"github.com/deadsy/rvdbg/util"
+)
+
+//-----------------------------------------------------------------------------
+
+// dcsr bits
+const (
+	dcsrEbreakM = (1 << 15)
+	dcsrEbreakS = (1 << 13)
+	dcsrEbreakU = (1 << 12)
+)
+
+//-----------------------------------------------------------------------------
+
+// BreakType is the type of breakpoint.
+type BreakType int
+
+// BreakType values.
+const (
+	BreakAuto     BreakType = iota // hardware if a trigger is available, else software
+	BreakHardware                  // trigger module
+	BreakSoftware                  // ebreak instruction in memory
+)
+
+func (t BreakType) String() string {
+	return [...]string{"auto", "hw", "sw"}[t]
+}
+
+// Breakpoint is a hart breakpoint.
+type Breakpoint struct {
+	ID      int       // breakpoint identifier
+	Type    BreakType // hardware or software
+	Addr    uint      // breakpoint address
+	Enabled bool      // is the breakpoint enabled?
+	trigger *Trigger  // trigger used by a hardware breakpoint
+	ins     []uint    // original instruction (16-bit parcels) for a software breakpoint
+}
+
+// set writes the breakpoint to the hart.
+func (bp *Breakpoint) set(dbg Debug) error {
+	hi := dbg.GetCurrentHart()
+	if bp.Type == BreakHardware {
+		return wrTrigger(dbg, bp.trigger.Index, tdata1Match(hi, bp.trigger.matchType(), mcontrolExecute), uint64(bp.Addr))
+	}
+	// Read the instruction as 2 x 16-bit values since a 32-bit
+	// instruction may only be 16-bit aligned.
+	ins, err := dbg.RdMem(16, bp.Addr, 2)
+	if err != nil {
+		return err
+	}
+	n := insLength(hi, bp.Addr, (ins[1]<<16)|ins[0])
+	var ebreak []uint
+	if n == 2 {
+		ebreak = []uint{uint(InsCEBREAK())}
+	} else {
+		x := uint(InsEBREAK())
+		ebreak = []uint{x & 0xffff, x >> 16}
+	}
+	bp.ins = ins[:len(ebreak)]
+	err = dbg.WrMem(16, bp.Addr, ebreak)
+	if err != nil {
+		return err
+	}
+	// check the write (it won't work for rom/flash)
+	x, err := dbg.RdMem(16, bp.Addr, uint(len(ebreak)))
+	if err != nil {
+		return err
+	}
+	for i := range x {
+		if x[i] != ebreak[i] {
+			// put back anything we might have changed
+			dbg.WrMem(16, bp.Addr, bp.ins)
+			bp.ins = nil
+			return fmt.Errorf("unable to write ebreak at 0x%x, not ram?", bp.Addr)
+		}
+	}
+	return dbg.FenceI()
+}
+
+// clr removes the breakpoint from the hart.
+func (bp *Breakpoint) clr(dbg Debug) error {
+	if bp.Type == BreakHardware {
+		return wrTrigger(dbg, bp.trigger.Index, 0, 0)
+	}
+	if bp.ins == nil {
+		return nil
+	}
+	err := dbg.WrMem(16, bp.Addr, bp.ins)
+	if err != nil {
+		return err
+	}
+	bp.ins = nil
+	return dbg.FenceI()
+}
+
+// insLength returns the length in bytes of an instruction.
+func insLength(hi *HartInfo, addr, ins uint) uint {
+	if hi.ISA != nil {
+		return hi.ISA.Disassemble(addr, ins).InsLength
+	}
+	if ins&3 != 3 {
+		return 2
+	}
+	return 4
+}
+
+// setEbreak sets/clears dcsr.ebreakm/s/u so ebreak enters debug mode.
+// They are set when there are software breakpoints or semihosting is enabled.
+func setEbreak(dbg Debug) error {
+	hi := dbg.GetCurrentHart()
+	enable := hi.semihosting
+	for _, bp := range hi.Breakpoints {
+		if bp.Type == BreakSoftware && bp.Enabled {
+			enable = true
+			break
+		}
+	}
+	bits := uint64(dcsrEbreakM)
+	if hi.SXLEN != 0 {
+		bits |= dcsrEbreakS
+	}
+	if hi.UXLEN != 0 {
+		bits |= dcsrEbreakU
+	}
+	dcsr, err := dbg.RdCSR(DCSR, 0)
+	if err != nil {
+		return err
+	}
+	if enable {
+		dcsr |= bits
+	} else {
+		dcsr &^= bits
+	}
+	return dbg.WrCSR(DCSR, 0, dcsr)
+}
+
+// SetSemihosting enables/disables ebreak semihosting calls on the current hart.
+func SetSemihosting(dbg Debug, enable bool) error {
+	dbg.GetCurrentHart().semihosting = enable
+	return setEbreak(dbg)
+}
+
+//-----------------------------------------------------------------------------
+
+// BreakpointsString returns a display string for the breakpoints of the current hart.
+func BreakpointsString(dbg Debug) string {
+	hi := dbg.GetCurrentHart()
+	if len(hi.Breakpoints) == 0 {
+		return "no breakpoints"
+	}
+	fmtAddr := util.UintFormat(hi.MXLEN)
+	s := [][]string{}
+	for _, bp := range hi.Breakpoints {
+		where := ""
+		if bp.Type == BreakHardware {
+			where = fmt.Sprintf("trigger %d", bp.trigger.Index)
+		}
+		s = append(s, []string{
+			fmt.Sprintf("%d", bp.ID),
+			fmt.Sprintf(fmtAddr, bp.Addr),
+			bp.Type.String(),
+			[]string{"disabled", "enabled"}[util.BoolToInt(bp.Enabled)],
+			where,
+		})
+	}
+	return cli.TableString(s, []int{0, 0, 0, 0, 0}, 1)
+}
+
+// lookupBreakpoint returns the index of a breakpoint within the breakpoint list.
+func lookupBreakpoint(hi *HartInfo, id int) (int, error) {
+	for i, bp := range hi.Breakpoints {
+		if bp.ID == id {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("no breakpoint %d", id)
+}
+
+// AddBreakpoint adds a breakpoint to the current hart.
+func AddBreakpoint(dbg Debug, addr uint, typ BreakType) (*Breakpoint, error) {
+	hi := dbg.GetCurrentHart()
+	for _, bp := range hi.Breakpoints {
+		if bp.Addr == addr {
+			return nil, fmt.Errorf("breakpoint %d is already at 0x%x", bp.ID, addr)
+		}
+	}
+	bp := &Breakpoint{
+		ID:      hi.nextBreakID,
+		Type:    typ,
+		Addr:    addr,
+		Enabled: true,
+	}
+	if typ != BreakSoftware {
+		t, err := allocTrigger(dbg, 1)
+		if err != nil {
+			if typ == BreakHardware || (err != ErrNoTriggers && err != ErrNoFreeTriggers) {
+				return nil, err
+			}
+			bp.Type = BreakSoftware
+		} else {
+			bp.Type = BreakHardware
+			bp.trigger = t[0]
+		}
+	}
+	err := bp.set(dbg)
+	if err != nil {
+		if bp.Type == BreakHardware {
+			bp.trigger.InUse = false
+			// leave the trigger disabled
+			bp.clr(dbg)
+		}
+		return nil, err
+	}
+	hi.nextBreakID++
+	hi.Breakpoints = append(hi.Breakpoints, bp)
+	if bp.Type == BreakSoftware {
+		err = setEbreak(dbg)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return bp, nil
+}
+
+// RemoveBreakpoint removes a breakpoint from the current hart.
+func RemoveBreakpoint(dbg Debug, id int) error {
+	hi := dbg.GetCurrentHart()
+	i, err := lookupBreakpoint(hi, id)
+	if err != nil {
+		return err
+	}
+	bp := hi.Breakpoints[i]
+	if bp.Enabled {
+		err = bp.clr(dbg)
+		if err != nil {
+			return err
+		}
+	}
+	if bp.Type == BreakHardware {
+		bp.trigger.InUse = false
+	}
+	hi.Breakpoints = append(hi.Breakpoints[:i], hi.Breakpoints[i+1:]...)
+	if bp.Type == BreakSoftware {
+		return setEbreak(dbg)
+	}
+	return nil
+}
+
+// EnableBreakpoint enables/disables a breakpoint on the current hart.
+func EnableBreakpoint(dbg Debug, id int, enable bool) error {
+	hi := dbg.GetCurrentHart()
+	i, err := lookupBreakpoint(hi, id)
+	if err != nil {
+		return err
+	}
+	bp := hi.Breakpoints[i]
+	if bp.Enabled == enable {
+		return nil
+	}
+	if enable {
+		err = bp.set(dbg)
+	} else {
+		err = bp.clr(dbg)
+	}
+	if err != nil {
+		return err
+	}
+	bp.Enabled = enable
+	if bp.Type == BreakSoftware {
+		return setEbreak(dbg)
+	}
+	return nil
+}
+
+// ClearBreakpoints clears the breakpoint/watchpoint state.
+// The trigger module and memory contents are lost after a reset.
+func (hi *HartInfo) ClearBreakpoints() {
+	hi.Triggers = nil
+	hi.Breakpoints = nil
+	hi.Watchpoints = nil
+}
+
+// SaveBreakpoints takes the breakpoints off the current hart before a reset.
+// Software breakpoints put back the original instruction so a stale ebreak
+// isn't left in memory. RestoreBreakpoints sets them again after the reset.
+func SaveBreakpoints(dbg Debug) ([]*Breakpoint, error) {
+	hi := dbg.GetCurrentHart()
+	for _, bp := range hi.Breakpoints {
+		if bp.Type == BreakSoftware && bp.Enabled {
+			err := bp.clr(dbg)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return hi.Breakpoints, nil
+}
+
+// RestoreBreakpoints sets saved breakpoints on the current hart after a reset.
+// Hardware breakpoints are given new triggers, the breakpoint ids are kept.
+func RestoreBreakpoints(dbg Debug, bps []*Breakpoint) error {
+	hi := dbg.GetCurrentHart()
+	for _, x := range bps {
+		bp := &Breakpoint{
+			ID:   x.ID,
+			Type: x.Type,
+			Addr: x.Addr,
+		}
+		if bp.Type == BreakHardware {
+			t, err := allocTrigger(dbg, 1)
+			if err != nil {
+				return fmt.Errorf("breakpoint %d: %v", bp.ID, err)
+			}
+			bp.trigger = t[0]
+		}
+		if x.Enabled {
+			err := bp.set(dbg)
+			if err != nil {
+				if bp.Type == BreakHardware {
+					bp.trigger.InUse = false
+					bp.clr(dbg)
+				}
+				return fmt.Errorf("breakpoint %d: %v", bp.ID, err)
+			}
+			bp.Enabled = true
+		}
+		hi.Breakpoints = append(hi.Breakpoints, bp)
+	}
+	return setEbreak(dbg)
+}
+
+// HitBreakpoint returns the breakpoint at the current pc (or nil).
+func HitBreakpoint(dbg Debug) (*Breakpoint, error) {
+	pc, err := dbg.RdCSR(DPC, 0)
+	if err != nil {
+		return nil, err
+	}
+	for _, bp := range dbg.GetCurrentHart().Breakpoints {
+		if bp.Enabled && bp.Addr == uint(pc) {
+			return bp, nil
+		}
+	}
+	return nil, nil
+}
+
+//-----------------------------------------------------------------------------
+
+// stepOver single steps the current hart. If the hart is halted at a
+// breakpoint (or by a watchpoint) the breakpoint is removed, the
+// instruction is executed with a single step, and the breakpoint is set
+// again. If force is false the step only occurs if there is a breakpoint
+// to step over.
+func stepOver(dbg Debug, ie, force bool) error {
+	bp, err := HitBreakpoint(dbg)
+	if err != nil {
+		return err
+	}
+	cause, err := GetHaltCause(dbg)
+	if err != nil {
+		return err
+	}
+	var wps []*Watchpoint
+	if cause == CauseTrigger {
+		// a load/store trigger fires before the access, step over it
+		wps = dbg.GetCurrentHart().Watchpoints
+	}
+	if bp == nil && len(wps) == 0 {
+		if force {
+			return dbg.StepHart(ie)
+		}
+		return nil
+	}
+	if bp != nil {
+		err = bp.clr(dbg)
+		if err != nil {
+			return err
+		}
+	}
+	for _, wp := range wps {
+		err = wp.clr(dbg)
+		if err != nil {
+			return err
+		}
+	}
+	serr := dbg.StepHart(ie)
+	for _, wp := range wps {
+		err = wp.set(dbg)
+		if err != nil {
+			return err
+		}
+	}
+	if bp != nil {
+		err = bp.set(dbg)
+		if err != nil {
+			return err
+		}
+	}
+	return serr
+}
+
+// Step single steps the current hart, stepping over any breakpoint at the pc.
+func Step(dbg Debug, ie bool) error {
+	return stepOver(dbg, ie, true)
+}
+
+// StepOverBreakpoint is called before resuming the current hart.
+// If the hart is halted at a breakpoint (or by a watchpoint) it is
+// stepped past it so the resume doesn't immediately halt again.
+func StepOverBreakpoint(dbg Debug) error {
+	err := stepOver(dbg, false, false)
+	if err != nil {
+		return fmt.Errorf("unable to step over breakpoint: %v", err)
+	}
+	return nil
+}
+
+//-----------------------------------------------------------------------------
@@ -27,9 +27,17 @@ func (hi *HartInfo) NewCsr() *soc.Device {
 				Registers: []soc.Register{
 					// User CSRs 0x000 - 0x0ff (read/write)
 					{Offset: 0x000, Name: "ustatus"},
-					{Offset: 0x001, Name: "fflags"},
-					{Offset: 0x002, Name: "frm"},
-					{Offset: 0x003, Name: "fcsr"},
+					{Offset: 0x001, Name: "fflags", Fields: fflagsFields()},
+					{Offset: 0x002, Name: "frm",
+						Fields: []soc.Field{
+							{Name: "frm", Msb: 2, Lsb: 0, Enums: RoundingMode},
+						},
+					},
+					{Offset: 0x003, Name: "fcsr",
+						Fields: append([]soc.Field{
+							{Name: "frm", Msb: 7, Lsb: 5, Enums: RoundingMode},
+						}, fflagsFields()...),
+					},
 					{Offset: 0x004, Name: "uie"},
 					{Offset: 0x005, Name: "utvec"},
 					{Offset: 0x040, Name: "uscratch"},
@@ -341,6 +349,7 @@ func (hi *HartInfo) NewCsr() *soc.Device {
 					{Offset: 0x7a1, Name: "tdata1"},
 					{Offset: 0x7a2, Name: "tdata2"},
 					{Offset: 0x7a3, Name: "tdata3"},
+					{Offset: 0x7a4, Name: "tinfo"},
 					// Machine Debug Mode Only CSRs 0x7b0 - 0x7bf (read/write)
 					{Offset: 0x7b0,
 						Name: "dcsr",
@@ -352,6 +361,7 @@ func (hi *HartInfo) NewCsr() *soc.Device {
 							{Name: "ebreakh", Msb: 14, Lsb: 14},
 							{Name: "ebreaks", Msb: 13, Lsb: 13},
 							{Name: "ebreaku", Msb: 12, Lsb: 12},
+							{Name: "stepie", Msb: 11, Lsb: 11},
 							{Name: "stopcycle", Msb: 10, Lsb: 10},
 							{Name: "stoptime", Msb: 9, Lsb: 9},
 							{Name: "cause", Msb: 8, Lsb: 6},
@@ -379,6 +389,9 @@ func (hi *HartInfo) NewCsr() *soc.Device {
 		},
 	}
 
+	// pmpcfg4-15 and pmpaddr16-63 (priv 1.12)
+	csr.Peripherals[0].Registers = append(csr.Peripherals[0].Registers, pmpRegisters()...)
+
 	// modifications decodes for RV64
 	if hi.MXLEN == 64 {
 		p, _ := csr.GetPeripheral("CSR")
@@ -399,6 +412,17 @@ func (hi *HartInfo) NewCsr() *soc.Device {
 			p.RemoveRegister(fmt.Sprintf("mhpmcounter%dh", i))
 			p.RemoveRegister(fmt.Sprintf("hpmcounter%dh", i))
 		}
+		for i := 1; i < 16; i += 2 {
+			p.RemoveRegister(fmt.Sprintf("pmpcfg%d", i))
+		}
+	}
+
+	// vector extension
+	if CheckExtMISA(hi.MISA, 'v') {
+		p, _ := csr.GetPeripheral("CSR")
+		p.Registers = append(p.Registers, vectorRegisters(hi.MXLEN)...)
+		r, _ := p.GetRegister("mstatus")
+		r.Fields = append(r.Fields, soc.Field{Name: "vs", Msb: 10, Lsb: 9, Enums: soc.Enum{0: "off", 1: "initial", 2: "clean", 3: "dirty"}})
 	}
 
 	// TODO differences to DCSR decode based on debugger version
@@ -414,10 +438,24 @@ const (
 	FFLAGS    = 0x001
 	FRM       = 0x002
 	FCSR      = 0x003
+	VSTART    = 0x008
+	VXSAT     = 0x009
+	VXRM      = 0x00a
+	VCSR      = 0x00f
 	SSCRATCH  = 0x140
+	SATP      = 0x180
 	MSTATUS   = 0x300
 	MISA      = 0x301
+	MIE       = 0x304
 	MSCRATCH  = 0x340
+	MIP       = 0x344
+	PMPCFG0   = 0x3a0
+	PMPADDR0  = 0x3b0
+	TSELECT   = 0x7a0
+	TDATA1    = 0x7a1
+	TDATA2    = 0x7a2
+	TDATA3    = 0x7a3
+	TINFO     = 0x7a4
 	DCSR      = 0x7b0
 	DPC       = 0x7b1
 	DSCRATCH0 = 0x7b2
@@ -426,6 +464,9 @@ const (
 	MARCHID   = 0xf12
 	MIMPID    = 0xf13
 	MHARTID   = 0xf14
+	VL        = 0xc20
+	VTYPE     = 0xc21
+	VLENB     = 0xc22
 )
 
 // CSR address modes.
@@ -435,6 +476,135 @@ const modeSupervisor = (1 << 8)
 const modeHypervisor = (2 << 8)
 const modeMachine = (3 << 8)
 
+//-----------------------------------------------------------------------------
+// floating point control and status
+
+// RoundingMode is the enumeration of the fcsr.frm field.
+var RoundingMode = soc.Enum{
+	0: "rne",
+	1: "rtz",
+	2: "rdn",
+	3: "rup",
+	4: "rmm",
+	7: "dyn",
+}
+
+// FflagsName is the name of each fcsr.fflags bit.
+var FflagsName = [5]string{"nx", "uf", "of", "dz", "nv"}
+
+// fflagsFields returns the accrued exception flag fields.
+func fflagsFields() []soc.Field {
+	f := []soc.Field{}
+	for i, name := range FflagsName {
+		f = append(f, soc.Field{Name: name, Msb: uint(i), Lsb: uint(i)})
+	}
+	return f
+}
+
+//-----------------------------------------------------------------------------
+// vector control and status
+
+// VectorRounding is the enumeration of the vxrm field.
+var VectorRounding = soc.Enum{
+	0: "rnu",
+	1: "rne",
+	2: "rdn",
+	3: "rod",
+}
+
+// VectorSEW is the enumeration of the vtype.vsew field.
+var VectorSEW = soc.Enum{
+	0: "e8",
+	1: "e16",
+	2: "e32",
+	3: "e64",
+}
+
+// VectorLMUL is the enumeration of the vtype.vlmul field.
+var VectorLMUL = soc.Enum{
+	0: "m1",
+	1: "m2",
+	2: "m4",
+	3: "m8",
+	5: "mf8",
+	6: "mf4",
+	7: "mf2",
+}
+
+// vectorRegisters returns the vector extension CSRs.
+func vectorRegisters(xlen uint) []soc.Register {
+	return []soc.Register{
+		{Offset: VSTART, Name: "vstart"},
+		{Offset: VXSAT, Name: "vxsat",
+			Fields: []soc.Field{
+				{Name: "vxsat", Msb: 0, Lsb: 0},
+			},
+		},
+		{Offset: VXRM, Name: "vxrm",
+			Fields: []soc.Field{
+				{Name: "vxrm", Msb: 1, Lsb: 0, Enums: VectorRounding},
+			},
+		},
+		{Offset: VCSR, Name: "vcsr",
+			Fields: []soc.Field{
+				{Name: "vxrm", Msb: 2, Lsb: 1, Enums: VectorRounding},
+				{Name: "vxsat", Msb: 0, Lsb: 0},
+			},
+		},
+		{Offset: VL, Name: "vl"},
+		{Offset: VTYPE, Name: "vtype",
+			Fields: []soc.Field{
+				{Name: "vill", Msb: xlen - 1, Lsb: xlen - 1},
+				{Name: "vma", Msb: 7, Lsb: 7},
+				{Name: "vta", Msb: 6, Lsb: 6},
+				{Name: "vsew", Msb: 5, Lsb: 3, Enums: VectorSEW},
+				{Name: "vlmul", Msb: 2, Lsb: 0, Enums: VectorLMUL},
+			},
+		},
+		{Offset: VLENB, Name: "vlenb"},
+	}
+}
+
+// mstatus.vs field values
+const (
+	MstatusVS        = (3 << 9)
+	MstatusVSInitial = (1 << 9)
+	MstatusVSDirty   = (3 << 9)
+)
+
+// EnableVector turns on the vector unit of a halted hart.
+// Vector CSRs and instructions are illegal with mstatus.vs == off, so
+// mstatus.vs is set to initial. The old mstatus value is returned so the
+// caller can restore it.
+func EnableVector(dbg Debug) (uint64, error) {
+	mstatus, err := dbg.RdCSR(MSTATUS, 0)
+	if err != nil {
+		return 0, err
+	}
+	if mstatus&MstatusVS == 0 {
+		err = dbg.WrCSR(MSTATUS, 0, mstatus|MstatusVSInitial)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return mstatus, nil
+}
+
+//-----------------------------------------------------------------------------
+// physical memory protection
+
+// pmpRegisters returns the pmp CSRs beyond those in the base table.
+func pmpRegisters() []soc.Register {
+	r := []soc.Register{}
+	for i := uint(4); i < 16; i++ {
+		r = append(r, soc.Register{Offset: PMPCFG0 + i, Name: fmt.Sprintf("pmpcfg%d", i)})
+	}
+	for i := uint(16); i < 64; i++ {
+		r = append(r, soc.Register{Offset: PMPADDR0 + i, Name: fmt.Sprintf("pmpaddr%d", i)})
+	}
+	return r
+}
+
 //-----------------------------------------------------------------------------
 // MISA
 
@@ -484,6 +654,11 @@ func GetCSRSize(reg uint, hi *HartInfo) uint {
 			return 32
 		}
 		return 0
+	case VSTART, VXSAT, VXRM, VCSR, VL, VTYPE, VLENB:
+		if CheckExtMISA(hi.MISA, 'v') {
+			return hi.MXLEN
+		}
+		return 0
 	case DPC:
 		return hi.DXLEN
 	}
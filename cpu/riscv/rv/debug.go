@@ -14,6 +14,7 @@ import (
 	cli "github.com/deadsy/go-cli"
 	"github.com/deadsy/rvda"
 	"github.com/deadsy/rvdbg/soc"
+	"github.com/deadsy/rvdbg/util"
 )
 
 //-----------------------------------------------------------------------------
@@ -40,6 +41,45 @@ func (s HartState) String() string {
 	return "unknown"
 }
 
+// HaltCause is the reason a hart entered debug mode (dcsr.cause).
+type HaltCause uint
+
+// HaltCause values.
+const (
+	CauseNone         HaltCause = 0 // no cause
+	CauseEbreak       HaltCause = 1 // ebreak instruction
+	CauseTrigger      HaltCause = 2 // trigger module
+	CauseHaltReq      HaltCause = 3 // debugger halt request
+	CauseStep         HaltCause = 4 // single step
+	CauseResetHaltReq HaltCause = 5 // halt on reset
+)
+
+var causeName = map[HaltCause]string{
+	CauseEbreak:       "ebreak",
+	CauseTrigger:      "trigger",
+	CauseHaltReq:      "halt request",
+	CauseStep:         "step",
+	CauseResetHaltReq: "reset halt request",
+}
+
+func (c HaltCause) String() string {
+	if name, ok := causeName[c]; ok {
+		return name
+	}
+	return fmt.Sprintf("unknown(%d)", c)
+}
+
+// GetHaltCause returns the reason the current hart entered debug mode.
+func GetHaltCause(dbg Debug) (HaltCause, error) {
+	dcsr, err := dbg.RdCSR(DCSR, 0)
+	if err != nil {
+		return CauseNone, err
+	}
+	return HaltCause(util.Bits(uint(dcsr), 8, 6)), nil
+}
+
+//-----------------------------------------------------------------------------
+
 // HartInfo stores generic hart information.
 type HartInfo struct {
 	ID      int         // hart identifier
@@ -51,10 +91,18 @@ type HartInfo struct {
 	HXLEN   uint        // hypervisor XLEN (0 == no H-mode)
 	DXLEN   uint        // debug XLEN
 	FLEN    uint        // foating point register width (0 == no floating point)
+	VLENB   uint        // vector register width in bytes (0 == no vector)
 	MISA    uint        // MISA value
 	MHARTID uint        // MHARTID value
 	CSR     *soc.Device // CSR registers/fields
 	ISA     *rvda.ISA   // ISA for the disassembler
+	// breakpoints/watchpoints
+	Triggers    []*Trigger    // trigger module entries (nil == not enumerated)
+	Breakpoints []*Breakpoint // breakpoints
+	Watchpoints []*Watchpoint // watchpoints
+	nextBreakID int           // identifier for the next breakpoint
+	nextWatchID int           // identifier for the next watchpoint
+	semihosting bool          // ebreak enters debug mode for semihosting calls
 }
 
 func xlenString(n uint, msg string) string {
@@ -74,6 +122,7 @@ func (hi *HartInfo) String() string {
 	s = append(s, []string{"uxlen", xlenString(hi.UXLEN, "u-mode")})
 	s = append(s, []string{"hxlen", xlenString(hi.HXLEN, "h-mode")})
 	s = append(s, []string{"flen", xlenString(hi.FLEN, "floating point")})
+	s = append(s, []string{"vlenb", xlenString(hi.VLENB, "vector")})
 	s = append(s, []string{"dxlen", fmt.Sprintf("%d", hi.DXLEN)})
 	return cli.TableString(s, []int{0, 0}, 1)
 }
@@ -88,8 +137,13 @@ type Debug interface {
 	GetHartInfo(id int) (*HartInfo, error)    // return the info structure for hart id
 	GetCurrentHart() *HartInfo                // get the info structure for the current hart
 	SetCurrentHart(id int) (*HartInfo, error) // set the current hart
+	GetHartState() (HartState, error)         // read the state of the current hart
 	HaltHart() error                          // halt the current hart
 	ResumeHart() error                        // resume the current hart
+	HaltAll() (bool, error)                   // halt all harts (true: halted together, false: one at a time)
+	ResumeAll() (bool, error)                 // resume all harts (true: resumed together, false: one at a time)
+	StepHart(ie bool) error                   // single step the current hart (ie: enable interrupts)
+	Reset(halt bool) error                    // reset the system (halt: halt the harts after reset)
 	// registers
 	RdGPR(reg, size uint) (uint64, error)   // read general purpose register
 	RdFPR(reg, size uint) (uint64, error)   // read floating point register
@@ -97,10 +151,14 @@ type Debug interface {
 	WrGPR(reg, size uint, val uint64) error // write general purpose register
 	WrFPR(reg, size uint, val uint64) error // write floating point register
 	WrCSR(reg, size uint, val uint64) error // write control and status register
+	RdVR(reg uint) ([]uint8, error)         // read vector register (VLENB bytes)
+	WrVR(reg uint, val []uint8) error       // write vector register (VLENB bytes)
 	// memory
 	GetAddressSize() uint                      // get address size in bits
 	RdMem(width, addr, n uint) ([]uint, error) // read width-bit memory buffer
 	WrMem(width, addr uint, val []uint) error  // write width-bit memory buffer
+	GetConfigAddress() (uint, error)           // get the address of the configuration string/dtb
+	FenceI() error                             // run fence.i so the current hart fetches modified code
 	// test
 	Test1() string
 	Test2() string
@@ -27,6 +27,8 @@ const (
 	opcodeSRLI    = 0x00005013 // srli
 	opcodeADDI    = 0x00000013 // addi
 	opcodeEBREAK  = 0x00100073 // ebreak
+	opcodeFENCEI  = 0x0000100f // fence.i
+	opcodeCEBREAK = 0x9002     // c.ebreak
 	opcodeCSRRW   = 0x00001073 // csrrw
 	opcodeCSRRS   = 0x00002073 // csrrs
 	opcodeCSRRSI  = 0x00006073 // csrrsi
@@ -38,6 +40,10 @@ const (
 	opcodeFSD     = 0x00003027 // fsd
 	opcodeFLW     = 0x00002007 // flw
 	opcodeFSW     = 0x00002027 // fsw
+	opcodeVSETVLI = 0x00007057 // vsetvli
+	opcodeVSETVL  = 0x80007057 // vsetvl
+	opcodeVLE8    = 0x02000007 // vle8.v
+	opcodeVSE8    = 0x02000027 // vse8.v
 )
 
 //-----------------------------------------------------------------------------
@@ -97,6 +103,16 @@ func InsEBREAK() uint32 {
 	return uint32(opcodeEBREAK)
 }
 
+// InsFENCEI returns "fence.i"
+func InsFENCEI() uint32 {
+	return uint32(opcodeFENCEI)
+}
+
+// InsCEBREAK returns "c.ebreak"
+func InsCEBREAK() uint16 {
+	return uint16(opcodeCEBREAK)
+}
+
 // InsCSRR returns "csrr rd, csr"
 func InsCSRR(rd, csr uint) uint32 {
 	// csrrs rd, csr, x0
@@ -154,4 +170,48 @@ func InsFLW(rd, ofs, rs1 uint) uint32 {
 	return uint32((util.Bits(ofs, 11, 0) << 20) | (rs1 << 15) | (rd << 7) | opcodeFLW)
 }
 
+// InsVSETVLI returns "vsetvli rd, rs1, vtypei"
+func InsVSETVLI(rd, rs1, vtypei uint) uint32 {
+	return uint32((util.Bits(vtypei, 10, 0) << 20) | (rs1 << 15) | (rd << 7) | opcodeVSETVLI)
+}
+
+// InsVSETVL returns "vsetvl rd, rs1, rs2"
+func InsVSETVL(rd, rs1, rs2 uint) uint32 {
+	return uint32((rs2 << 20) | (rs1 << 15) | (rd << 7) | opcodeVSETVL)
+}
+
+// InsVLE8 returns "vle8.v vd, (rs1)"
+func InsVLE8(vd, rs1 uint) uint32 {
+	return uint32((rs1 << 15) | (vd << 7) | opcodeVLE8)
+}
+
+// InsVSE8 returns "vse8.v vs3, (rs1)"
+func InsVSE8(vs3, rs1 uint) uint32 {
+	return uint32((rs1 << 15) | (vs3 << 7) | opcodeVSE8)
+}
+
+//-----------------------------------------------------------------------------
+
+// InsIsCall returns true if the instruction is a call.
+// That is: jal/jalr with a link register, c.jal (rv32 only) or c.jalr.
+func InsIsCall(ins, xlen uint) bool {
+	if ins&3 == 3 {
+		// 32-bit instruction
+		opcode := ins & 0x7f
+		rd := util.Bits(ins, 11, 7)
+		return (opcode == 0x6f || opcode == 0x67) && rd != RegZero
+	}
+	// 16-bit instruction
+	ins &= 0xffff
+	if xlen == 32 && ins&0xe003 == 0x2001 {
+		// c.jal
+		return true
+	}
+	if ins&0xf07f == 0x9002 && util.Bits(ins, 11, 7) != RegZero {
+		// c.jalr (rs1 == 0 is c.ebreak)
+		return true
+	}
+	return false
+}
+
 //-----------------------------------------------------------------------------
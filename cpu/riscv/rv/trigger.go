@@ -0,0 +1,326 @@
+//-----------------------------------------------------------------------------
+/*
+
+RISC-V Trigger Module
+
+Hardware breakpoints and watchpoints are implemented using the trigger
+module CSRs (tselect, tinfo, tdata1, tdata2). This code is generic across debugger
+versions and only uses the RdCSR/WrCSR methods of the Debug interface.
+
+*/
+//-----------------------------------------------------------------------------
+
+package rv
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	cli "github.com/deadsy/go-cli"
+	"github.com/deadsy/rvdbg/util"
+)
+
+//-----------------------------------------------------------------------------
+
+// TriggerType is the type of a trigger (tdata1.type).
+type TriggerType uint
+
+// TriggerType values.
+const (
+	TriggerNone      TriggerType = 0  // no trigger at this index
+	TriggerLegacy    TriggerType = 1  // legacy SiFive address match
+	TriggerMcontrol  TriggerType = 2  // address/data match
+	TriggerIcount    TriggerType = 3  // instruction count
+	TriggerItrigger  TriggerType = 4  // interrupt
+	TriggerEtrigger  TriggerType = 5  // exception
+	TriggerMcontrol6 TriggerType = 6  // address/data match (debug spec 1.0)
+	TriggerDisabled  TriggerType = 15 // trigger exists but is disabled
+)
+
+var triggerName = map[TriggerType]string{
+	TriggerLegacy:    "legacy",
+	TriggerMcontrol:  "mcontrol",
+	TriggerIcount:    "icount",
+	TriggerItrigger:  "itrigger",
+	TriggerEtrigger:  "etrigger",
+	TriggerMcontrol6: "mcontrol6",
+	TriggerDisabled:  "disabled",
+}
+
+func (t TriggerType) String() string {
+	if name, ok := triggerName[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("type%d", t)
+}
+
+// maxTriggers is the limit on the number of triggers we will enumerate.
+const maxTriggers = 64
+
+//-----------------------------------------------------------------------------
+// tdata1 values
+
+// mcontrol/mcontrol6 bits
+const (
+	mcontrolHit     = (1 << 20)
+	mcontrol6Hit1   = (1 << 25)
+	mcontrol6Hit0   = (1 << 22)
+	mcontrolAction  = (1 << 12) // action = 1, enter debug mode
+	mcontrolChain   = (1 << 11)
+	mcontrolM       = (1 << 6)
+	mcontrolS       = (1 << 4)
+	mcontrolU       = (1 << 3)
+	mcontrolExecute = (1 << 2)
+	mcontrolStore   = (1 << 1)
+	mcontrolLoad    = (1 << 0)
+)
+
+// mcontrol/mcontrol6 match values
+const (
+	matchEqual = (0 << 7) // address == tdata2
+	matchNapot = (1 << 7) // address matches the NAPOT range in tdata2
+	matchGE    = (2 << 7) // address >= tdata2
+	matchLT    = (3 << 7) // address < tdata2
+)
+
+// tdata1Type returns the type field of a tdata1 value.
+func tdata1Type(x uint64, xlen uint) TriggerType {
+	return TriggerType(util.Bits(uint(x), xlen-1, xlen-4))
+}
+
+// tdata1Dmode returns the dmode bit of a tdata1 value.
+func tdata1Dmode(xlen uint) uint64 {
+	return 1 << (xlen - 5)
+}
+
+// tdata1Hit returns true if the hit bit(s) of a tdata1 value are set.
+func tdata1Hit(x uint64, xlen uint) bool {
+	switch tdata1Type(x, xlen) {
+	case TriggerMcontrol:
+		return x&mcontrolHit != 0
+	case TriggerMcontrol6:
+		return x&(mcontrol6Hit0|mcontrol6Hit1) != 0
+	}
+	return false
+}
+
+// tdata1Match returns a tdata1 value for a type 2 (mcontrol) or type 6 (mcontrol6) trigger.
+func tdata1Match(hi *HartInfo, typ TriggerType, bits uint64) uint64 {
+	x := (uint64(typ) << (hi.MXLEN - 4)) | tdata1Dmode(hi.MXLEN) | mcontrolAction | mcontrolM | bits
+	if hi.SXLEN != 0 {
+		x |= mcontrolS
+	}
+	if hi.UXLEN != 0 {
+		x |= mcontrolU
+	}
+	return x
+}
+
+//-----------------------------------------------------------------------------
+
+// Trigger is an entry in the trigger module.
+type Trigger struct {
+	Index int  // tselect index
+	Types uint // bitmap of supported trigger types
+	InUse bool // is this trigger being used by a breakpoint?
+}
+
+// Supports returns true if the trigger supports a type.
+func (t *Trigger) Supports(typ TriggerType) bool {
+	return t.Types&(1<<typ) != 0
+}
+
+// matchType returns the address/data match type supported by the trigger.
+func (t *Trigger) matchType() TriggerType {
+	if t.Supports(TriggerMcontrol) {
+		return TriggerMcontrol
+	}
+	return TriggerMcontrol6
+}
+
+func (t *Trigger) typeString() string {
+	s := []string{}
+	for i := TriggerLegacy; i < TriggerDisabled; i++ {
+		if t.Supports(i) {
+			s = append(s, i.String())
+		}
+	}
+	return strings.Join(s, ",")
+}
+
+// rdTrigger selects and reads the tdata1/tdata2 values of a trigger.
+func rdTrigger(dbg Debug, idx int) (uint64, uint64, error) {
+	err := dbg.WrCSR(TSELECT, 0, uint64(idx))
+	if err != nil {
+		return 0, 0, err
+	}
+	tdata1, err := dbg.RdCSR(TDATA1, 0)
+	if err != nil {
+		return 0, 0, err
+	}
+	tdata2, err := dbg.RdCSR(TDATA2, 0)
+	if err != nil {
+		return 0, 0, err
+	}
+	return tdata1, tdata2, nil
+}
+
+// wrTrigger selects and writes the tdata1/tdata2 values of a trigger.
+func wrTrigger(dbg Debug, idx int, tdata1, tdata2 uint64) error {
+	err := dbg.WrCSR(TSELECT, 0, uint64(idx))
+	if err != nil {
+		return err
+	}
+	// disable the trigger while we change it
+	err = dbg.WrCSR(TDATA1, 0, 0)
+	if err != nil {
+		return err
+	}
+	if tdata1 == 0 {
+		return nil
+	}
+	err = dbg.WrCSR(TDATA2, 0, tdata2)
+	if err != nil {
+		return err
+	}
+	err = dbg.WrCSR(TDATA1, 0, tdata1)
+	if err != nil {
+		return err
+	}
+	// the fields are WARL, check the value stuck
+	x, err := dbg.RdCSR(TDATA1, 0)
+	if err != nil {
+		return err
+	}
+	if x&tdata1 != tdata1 {
+		return fmt.Errorf("trigger %d: unable to set tdata1 (wr 0x%x rd 0x%x)", idx, tdata1, x)
+	}
+	return nil
+}
+
+// GetTriggers enumerates the triggers of the current hart.
+func GetTriggers(dbg Debug) ([]*Trigger, error) {
+	hi := dbg.GetCurrentHart()
+	if hi.Triggers != nil {
+		return hi.Triggers, nil
+	}
+	triggers := []*Trigger{}
+	for i := 0; i < maxTriggers; i++ {
+		err := dbg.WrCSR(TSELECT, 0, uint64(i))
+		if err != nil {
+			if i == 0 {
+				return nil, err
+			}
+			break
+		}
+		x, err := dbg.RdCSR(TSELECT, 0)
+		if err != nil {
+			return nil, err
+		}
+		if x != uint64(i) {
+			// no more triggers
+			break
+		}
+		var types uint
+		tinfo, err := dbg.RdCSR(TINFO, 0)
+		if err == nil {
+			types = util.Bits(uint(tinfo), 15, 0)
+		} else {
+			// no tinfo, use the type from tdata1
+			tdata1, err := dbg.RdCSR(TDATA1, 0)
+			if err != nil {
+				return nil, err
+			}
+			types = 1 << tdata1Type(tdata1, hi.MXLEN)
+		}
+		if types == 1<<TriggerNone {
+			// no more triggers
+			break
+		}
+		triggers = append(triggers, &Trigger{Index: i, Types: types})
+	}
+	hi.Triggers = triggers
+	return triggers, nil
+}
+
+// Trigger allocation errors.
+var (
+	ErrNoTriggers     = errors.New("no triggers implemented")
+	ErrNoFreeTriggers = errors.New("no free triggers")
+)
+
+// isMatch returns true if the trigger is free and supports address/data matching.
+func (t *Trigger) isMatch() bool {
+	return !t.InUse && (t.Supports(TriggerMcontrol) || t.Supports(TriggerMcontrol6))
+}
+
+// allocTrigger returns n consecutive free address/data match triggers.
+// Consecutive triggers are needed for chaining.
+func allocTrigger(dbg Debug, n int) ([]*Trigger, error) {
+	triggers, err := GetTriggers(dbg)
+	if err != nil {
+		return nil, err
+	}
+	if len(triggers) == 0 {
+		return nil, ErrNoTriggers
+	}
+	for i := 0; i+n <= len(triggers); i++ {
+		t := triggers[i : i+n]
+		ok := true
+		for j := range t {
+			if !t[j].isMatch() || t[j].Index != t[0].Index+j {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			for j := range t {
+				t[j].InUse = true
+			}
+			return t, nil
+		}
+	}
+	return nil, ErrNoFreeTriggers
+}
+
+// freeTrigger marks triggers as unused.
+func freeTrigger(t []*Trigger) {
+	for i := range t {
+		t[i].InUse = false
+	}
+}
+
+// TriggersString returns a display string for the triggers of the current hart.
+func TriggersString(dbg Debug) (string, error) {
+	triggers, err := GetTriggers(dbg)
+	if err != nil {
+		return "", err
+	}
+	if len(triggers) == 0 {
+		return "no triggers", nil
+	}
+	xlen := dbg.GetCurrentHart().MXLEN
+	s := [][]string{}
+	for _, t := range triggers {
+		tdata1, tdata2, err := rdTrigger(dbg, t.Index)
+		if err != nil {
+			return "", err
+		}
+		hit := ""
+		if tdata1Hit(tdata1, xlen) {
+			hit = "hit"
+		}
+		s = append(s, []string{
+			fmt.Sprintf("%d", t.Index),
+			t.typeString(),
+			tdata1Type(tdata1, xlen).String(),
+			fmt.Sprintf(util.UintFormat(xlen), tdata2),
+			[]string{"", "in use"}[util.BoolToInt(t.InUse)],
+			hit,
+		})
+	}
+	return cli.TableString(s, []int{0, 0, 0, 0, 0, 0}, 1), nil
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,202 @@
+//-----------------------------------------------------------------------------
+/*
+
+RISC-V Virtual Memory
+
+Walk the page tables to translate a virtual address to a physical address.
+Supports Sv32, Sv39 and Sv48 as selected by satp.mode.
+
+On priv 1.9.1 harts (E.g. the K210) satp is sptbr, it holds the ASID and
+root page table PPN and the paging mode is selected by mstatus.vm.
+
+The translation is done by the debugger. Accessing memory through the hart
+with dcsr.mprven and mstatus.mprv (so the hart does the translation) is not
+implemented.
+
+*/
+//-----------------------------------------------------------------------------
+
+package rv
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/deadsy/rvdbg/util"
+)
+
+//-----------------------------------------------------------------------------
+
+// pageShift is the shift for the 4KiB base page size.
+const pageShift = 12
+
+// PTE flag bits.
+const (
+	pteV = (1 << 0) // valid
+	pteR = (1 << 1) // readable
+	pteW = (1 << 2) // writeable
+	pteX = (1 << 3) // executable
+	pteU = (1 << 4) // user mode accessible
+	pteG = (1 << 5) // global mapping
+	pteA = (1 << 6) // accessed
+	pteD = (1 << 7) // dirty
+)
+
+// vmMode describes a paged virtual memory mode.
+type vmMode struct {
+	name    string
+	levels  int  // number of page table levels
+	pteSize uint // size of a page table entry in bytes
+	vpnBits uint // number of virtual page number bits per level
+	ppnMsb  uint // msb of the physical page number in the PTE
+	vaBits  uint // number of virtual address bits
+}
+
+var sv32 = &vmMode{"sv32", 2, 4, 10, 31, 32}
+var sv39 = &vmMode{"sv39", 3, 8, 9, 53, 39}
+var sv48 = &vmMode{"sv48", 4, 8, 9, 53, 48}
+
+// getMode returns the paging mode and root page table address.
+func getMode(dbg Debug, hi *HartInfo) (*vmMode, uint, error) {
+	satp, err := dbg.RdCSR(SATP, 0)
+	if err != nil {
+		return nil, 0, err
+	}
+	x := uint(satp)
+	if hi.MXLEN == 32 {
+		if util.Bit(x, 31) == 0 {
+			return nil, 0, errors.New("satp.mode is bare")
+		}
+		return sv32, util.Bits(x, 21, 0) << pageShift, nil
+	}
+	switch util.Bits(x, 63, 60) {
+	case 8:
+		return sv39, util.Bits(x, 43, 0) << pageShift, nil
+	case 9:
+		return sv48, util.Bits(x, 43, 0) << pageShift, nil
+	case 0:
+		// try the priv 1.9.1 mstatus.vm field
+		mstatus, err := dbg.RdCSR(MSTATUS, 0)
+		if err != nil {
+			return nil, 0, err
+		}
+		// sptbr has the asid in 63:38 and the ppn in 37:0
+		switch util.Bits(uint(mstatus), 28, 24) {
+		case 9:
+			return sv39, util.Bits(x, 37, 0) << pageShift, nil
+		case 10:
+			return sv48, util.Bits(x, 37, 0) << pageShift, nil
+		}
+		return nil, 0, errors.New("satp.mode is bare")
+	}
+	return nil, 0, fmt.Errorf("satp.mode %d is not supported", util.Bits(x, 63, 60))
+}
+
+//-----------------------------------------------------------------------------
+
+// PTE is a page table entry read during a page table walk.
+type PTE struct {
+	Level int  // page table level
+	Addr  uint // physical address of the PTE
+	Val   uint // PTE value
+}
+
+// pteFlags returns a string for the PTE flag bits.
+func pteFlags(x uint) string {
+	const names = "vrwxugad"
+	s := []byte("--------")
+	for i := range s {
+		if x&(1<<uint(i)) != 0 {
+			s[len(s)-1-i] = names[i]
+		}
+	}
+	return string(s)
+}
+
+func (pte *PTE) String() string {
+	return fmt.Sprintf("level %d pte @ 0x%x = 0x%x %s", pte.Level, pte.Addr, pte.Val, pteFlags(pte.Val))
+}
+
+// Translation is the result of a virtual to physical address translation.
+type Translation struct {
+	Mode     string // paging mode
+	VA       uint   // virtual address
+	PA       uint   // physical address
+	PageSize uint   // page size in bytes
+	Walk     []PTE  // PTEs read during the walk
+	Fault    string // reason for a page fault ("" == no fault)
+}
+
+func (t *Translation) String() string {
+	s := []string{}
+	s = append(s, fmt.Sprintf("%s va 0x%x", t.Mode, t.VA))
+	for i := range t.Walk {
+		s = append(s, t.Walk[i].String())
+	}
+	if t.Fault != "" {
+		s = append(s, fmt.Sprintf("page fault: %s", t.Fault))
+	} else {
+		s = append(s, fmt.Sprintf("pa 0x%x (%s page)", t.PA, util.MemSize(t.PageSize)))
+	}
+	return strings.Join(s, "\n")
+}
+
+// Translate walks the page tables of the current hart to translate a virtual address.
+// The hart must be halted. Page faults are reported in the returned translation.
+func Translate(dbg Debug, va uint) (*Translation, error) {
+	hi := dbg.GetCurrentHart()
+	mode, a, err := getMode(dbg, hi)
+	if err != nil {
+		return nil, err
+	}
+	t := &Translation{
+		Mode: mode.name,
+		VA:   va,
+	}
+	// check the upper virtual address bits are a sign extension of the msb
+	if mode.vaBits < hi.MXLEN {
+		upper := va >> (mode.vaBits - 1)
+		if upper != 0 && upper != util.Mask(hi.MXLEN-mode.vaBits, 0) {
+			t.Fault = "virtual address is not canonical"
+			return t, nil
+		}
+	}
+	width := mode.pteSize << 3
+	for i := mode.levels - 1; i >= 0; i-- {
+		shift := pageShift + uint(i)*mode.vpnBits
+		vpn := util.Bits(va, shift+mode.vpnBits-1, shift)
+		addr := a + vpn*mode.pteSize
+		x, err := dbg.RdMem(width, addr, 1)
+		if err != nil {
+			return nil, err
+		}
+		pte := x[0]
+		t.Walk = append(t.Walk, PTE{i, addr, pte})
+		if pte&pteV == 0 {
+			t.Fault = "pte is not valid"
+			return t, nil
+		}
+		if pte&pteR == 0 && pte&pteW != 0 {
+			t.Fault = "pte is write-only"
+			return t, nil
+		}
+		ppn := util.Bits(pte, mode.ppnMsb, 10)
+		if pte&(pteR|pteX) != 0 {
+			// leaf pte
+			if i != 0 && ppn&util.Mask(shift-pageShift-1, 0) != 0 {
+				t.Fault = "misaligned superpage"
+				return t, nil
+			}
+			t.PageSize = 1 << shift
+			t.PA = ((ppn << pageShift) &^ (t.PageSize - 1)) | (va & (t.PageSize - 1))
+			return t, nil
+		}
+		// pointer to the next level
+		a = ppn << pageShift
+	}
+	t.Fault = "no leaf pte"
+	return t, nil
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,236 @@
+//-----------------------------------------------------------------------------
+/*
+
+RISC-V Watchpoints
+
+Watchpoints use address match triggers (mcontrol/mcontrol6) for load,
+store or execute accesses. An address range uses a single NAPOT trigger
+if possible, otherwise a pair of chained triggers (>= start, < end).
+
+*/
+//-----------------------------------------------------------------------------
+
+package rv
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	cli "github.com/deadsy/go-cli"
+	"github.com/deadsy/rvdbg/util"
+)
+
+//-----------------------------------------------------------------------------
+
+// WatchType is the type of access matched by a watchpoint.
+type WatchType uint
+
+// WatchType values.
+const (
+	WatchRead      WatchType = mcontrolLoad
+	WatchWrite     WatchType = mcontrolStore
+	WatchReadWrite WatchType = mcontrolLoad | mcontrolStore
+	WatchExecute   WatchType = mcontrolExecute
+)
+
+var watchName = map[WatchType]string{
+	WatchRead:      "r",
+	WatchWrite:     "w",
+	WatchReadWrite: "rw",
+	WatchExecute:   "x",
+}
+
+func (t WatchType) String() string {
+	if name, ok := watchName[t]; ok {
+		return name
+	}
+	return "?"
+}
+
+//-----------------------------------------------------------------------------
+
+// Watchpoint is a hart watchpoint.
+type Watchpoint struct {
+	ID      int        // watchpoint identifier
+	Type    WatchType  // access type
+	Addr    uint       // start address
+	Size    uint       // size in bytes
+	Name    string     // symbol name (if any)
+	trigger []*Trigger // triggers used by the watchpoint
+}
+
+// isNapot returns true if the watchpoint range is a naturally aligned power of 2.
+func (wp *Watchpoint) isNapot() bool {
+	return wp.Size > 1 && wp.Size&(wp.Size-1) == 0 && wp.Addr&(wp.Size-1) == 0
+}
+
+// set writes the watchpoint to the hart triggers.
+func (wp *Watchpoint) set(dbg Debug) error {
+	hi := dbg.GetCurrentHart()
+	bits := uint64(wp.Type)
+	t := wp.trigger
+	if len(t) == 2 {
+		// chained range
+		err := wrTrigger(dbg, t[0].Index, tdata1Match(hi, t[0].matchType(), bits|matchGE|mcontrolChain), uint64(wp.Addr))
+		if err != nil {
+			return err
+		}
+		return wrTrigger(dbg, t[1].Index, tdata1Match(hi, t[1].matchType(), bits|matchLT), uint64(wp.Addr+wp.Size))
+	}
+	if wp.isNapot() {
+		return wrTrigger(dbg, t[0].Index, tdata1Match(hi, t[0].matchType(), bits|matchNapot), uint64(wp.Addr|((wp.Size>>1)-1)))
+	}
+	return wrTrigger(dbg, t[0].Index, tdata1Match(hi, t[0].matchType(), bits|matchEqual), uint64(wp.Addr))
+}
+
+// clr removes the watchpoint from the hart triggers.
+func (wp *Watchpoint) clr(dbg Debug) error {
+	for _, t := range wp.trigger {
+		err := wrTrigger(dbg, t.Index, 0, 0)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isHit returns true if the watchpoint triggers have the hit bit set.
+func (wp *Watchpoint) isHit(dbg Debug) (bool, error) {
+	xlen := dbg.GetCurrentHart().MXLEN
+	for _, t := range wp.trigger {
+		tdata1, _, err := rdTrigger(dbg, t.Index)
+		if err != nil {
+			return false, err
+		}
+		if tdata1Hit(tdata1, xlen) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (wp *Watchpoint) String() string {
+	s := fmt.Sprintf("%s 0x%x", wp.Type, wp.Addr)
+	if wp.Size > 1 {
+		s += fmt.Sprintf("-0x%x", wp.Addr+wp.Size-1)
+	}
+	if wp.Name != "" {
+		s += fmt.Sprintf(" (%s)", wp.Name)
+	}
+	return s
+}
+
+//-----------------------------------------------------------------------------
+
+// WatchpointsString returns a display string for the watchpoints of the current hart.
+func WatchpointsString(dbg Debug) string {
+	hi := dbg.GetCurrentHart()
+	if len(hi.Watchpoints) == 0 {
+		return "no watchpoints"
+	}
+	fmtAddr := util.UintFormat(hi.MXLEN)
+	s := [][]string{}
+	for _, wp := range hi.Watchpoints {
+		t := []string{}
+		for i := range wp.trigger {
+			t = append(t, fmt.Sprintf("%d", wp.trigger[i].Index))
+		}
+		s = append(s, []string{
+			fmt.Sprintf("%d", wp.ID),
+			wp.Type.String(),
+			fmt.Sprintf(fmtAddr, wp.Addr),
+			fmt.Sprintf("%d", wp.Size),
+			wp.Name,
+			fmt.Sprintf("trigger %s", strings.Join(t, ",")),
+		})
+	}
+	return cli.TableString(s, []int{0, 0, 0, 0, 0, 0}, 1)
+}
+
+// AddWatchpoint adds a watchpoint to the current hart.
+func AddWatchpoint(dbg Debug, typ WatchType, addr, size uint, name string) (*Watchpoint, error) {
+	hi := dbg.GetCurrentHart()
+	if size == 0 {
+		return nil, errors.New("watchpoint size is zero")
+	}
+	wp := &Watchpoint{
+		ID:   hi.nextWatchID,
+		Type: typ,
+		Addr: addr,
+		Size: size,
+		Name: name,
+	}
+	// try a single trigger
+	if size == 1 || wp.isNapot() {
+		t, err := allocTrigger(dbg, 1)
+		if err != nil {
+			return nil, err
+		}
+		wp.trigger = t
+		err = wp.set(dbg)
+		if err == nil {
+			hi.nextWatchID++
+			hi.Watchpoints = append(hi.Watchpoints, wp)
+			return wp, nil
+		}
+		wp.clr(dbg)
+		freeTrigger(t)
+		if size == 1 {
+			return nil, err
+		}
+		// no NAPOT support, try a chained range
+	}
+	t, err := allocTrigger(dbg, 2)
+	if err != nil {
+		return nil, err
+	}
+	wp.trigger = t
+	err = wp.set(dbg)
+	if err != nil {
+		wp.clr(dbg)
+		freeTrigger(t)
+		return nil, err
+	}
+	hi.nextWatchID++
+	hi.Watchpoints = append(hi.Watchpoints, wp)
+	return wp, nil
+}
+
+// RemoveWatchpoint removes a watchpoint from the current hart.
+func RemoveWatchpoint(dbg Debug, id int) error {
+	hi := dbg.GetCurrentHart()
+	for i, wp := range hi.Watchpoints {
+		if wp.ID == id {
+			err := wp.clr(dbg)
+			if err != nil {
+				return err
+			}
+			freeTrigger(wp.trigger)
+			hi.Watchpoints = append(hi.Watchpoints[:i], hi.Watchpoints[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("no watchpoint %d", id)
+}
+
+// HitWatchpoint returns the watchpoint that caused a trigger halt (or nil).
+func HitWatchpoint(dbg Debug) (*Watchpoint, error) {
+	hi := dbg.GetCurrentHart()
+	for _, wp := range hi.Watchpoints {
+		hit, err := wp.isHit(dbg)
+		if err != nil {
+			return nil, err
+		}
+		if hit {
+			return wp, nil
+		}
+	}
+	// The hit bits are optional. If there is only one watchpoint it must be the one.
+	if len(hi.Watchpoints) == 1 {
+		return hi.Watchpoints[0], nil
+	}
+	return nil, nil
+}
+
+//-----------------------------------------------------------------------------
@@ -26,7 +26,12 @@ import (
 
 // isHalted returns true if the currently selected hart is halted.
 func (dbg *Debug) isHalted() (bool, error) {
-	return false, errors.New("TODO")
+	// dmcontrol.haltnot is the halt notification for the selected hart
+	x, err := dbg.rdDbus(dmcontrol)
+	if err != nil {
+		return false, err
+	}
+	return x&haltNotification != 0, nil
 }
 
 // halt the current hart, return true if it was already halted.
@@ -56,6 +61,84 @@ func (dbg *Debug) resume() (bool, error) {
 	//return false, errors.New("TODO")
 }
 
+//-----------------------------------------------------------------------------
+// single step a hart
+
+// dcsr bits
+const (
+	dcsrHalt = (1 << 3)
+	dcsrStep = (1 << 2)
+)
+
+// step the current hart by a single instruction.
+// The 0.11 dcsr has no stepie bit, interrupts are disabled while stepping.
+func (dbg *Debug) step(ie bool) error {
+	if ie {
+		return errors.New("single step with interrupts enabled is not supported by the 0.11 debug module")
+	}
+	dcsr, err := rdCSR(dbg, rv.DCSR, 32)
+	if err != nil {
+		return err
+	}
+	// Clear dcsr.halt and set dcsr.step. The jump to the resume
+	// address at the end of the csr write executes a single instruction.
+	err = wrCSR(dbg, rv.DCSR, 32, (dcsr|dcsrStep)&^dcsrHalt)
+	if err != nil {
+		return err
+	}
+	// back in debug mode: set dcsr.halt and clear dcsr.step
+	return wrCSR(dbg, rv.DCSR, 32, (dcsr|dcsrHalt)&^dcsrStep)
+}
+
+//-----------------------------------------------------------------------------
+// reset
+
+// dmcontrol bits
+const ndreset = (1 << 1)
+
+// reset the system. The 0.11 debug module has no halt on reset, so a
+// halt request is made after the reset.
+func (dbg *Debug) reset(halt bool) error {
+	err := dbg.setDbus(dmcontrol, ndreset)
+	if err != nil {
+		return err
+	}
+	err = dbg.clrDbus(dmcontrol, ndreset)
+	if err != nil {
+		return err
+	}
+	// the debug ram contents are unknown
+	err = dbg.cache.reset()
+	if err != nil {
+		return err
+	}
+	// re-examine the harts
+	for i := range dbg.hart {
+		hi := dbg.hart[i]
+		hi.info.ClearBreakpoints()
+		err := hi.examine()
+		if err != nil {
+			return err
+		}
+		if halt {
+			_, err := dbg.halt()
+			if err != nil {
+				return err
+			}
+			// halt() doesn't check the result
+			halted, err := dbg.isHalted()
+			if err != nil {
+				return err
+			}
+			if !halted {
+				return fmt.Errorf("hart%d did not halt after reset", hi.info.ID)
+			}
+			hi.info.State = rv.Halted
+		}
+	}
+	return nil
+}
+
 //-----------------------------------------------------------------------------
 // access probing- setup pointers to access functions
 
@@ -420,4 +420,29 @@ func (dbg *Debug) WrMem(width, addr uint, val []uint) error {
 	return hi.wrMem(dbg, width, addr, val)
 }
 
+// FenceI runs a fence.i on the current hart so instruction fetches see
+// memory written by the debugger (software breakpoints, loaded code).
+func (dbg *Debug) FenceI() error {
+	dbg.cache.wr32(0, rv.InsFENCEI())
+	dbg.cache.wrResume(1)
+	return dbg.cache.flush(true)
+}
+
+//-----------------------------------------------------------------------------
+
+// configStringPtr is the address of the legacy config string pointer.
+const configStringPtr = 0x100c
+
+// GetConfigAddress returns the address of the configuration string/dtb.
+func (dbg *Debug) GetConfigAddress() (uint, error) {
+	x, err := dbg.RdMem(32, configStringPtr, 1)
+	if err != nil {
+		return 0, err
+	}
+	if x[0] == 0 {
+		return 0, fmt.Errorf("no config string pointer at 0x%x", configStringPtr)
+	}
+	return x[0], nil
+}
+
 //-----------------------------------------------------------------------------
@@ -9,6 +9,7 @@ RISC-V Debugger 0.11 Register Operations
 package rv11
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/deadsy/rvdbg/cpu/riscv/rv"
@@ -96,3 +97,16 @@ func (dbg *Debug) WrFPR(reg, size uint, val uint64) error {
 }
 
 //-----------------------------------------------------------------------------
+// vector registers
+
+// RdVR reads a vector register.
+func (dbg *Debug) RdVR(reg uint) ([]uint8, error) {
+	return nil, errors.New("vector register access is not supported")
+}
+
+// WrVR writes a vector register.
+func (dbg *Debug) WrVR(reg uint, val []uint8) error {
+	return errors.New("vector register access is not supported")
+}
+
+//-----------------------------------------------------------------------------
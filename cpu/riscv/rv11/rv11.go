@@ -232,6 +232,17 @@ func (dbg *Debug) SetCurrentHart(id int) (*rv.HartInfo, error) {
 	return &dbg.hart[dbg.hartid].info, err
 }
 
+// GetHartState reads the state of the current hart.
+func (dbg *Debug) GetHartState() (rv.HartState, error) {
+	halted, err := dbg.isHalted()
+	if err != nil {
+		return rv.Unknown, err
+	}
+	state := []rv.HartState{rv.Running, rv.Halted}[util.BoolToInt(halted)]
+	dbg.hart[dbg.hartid].info.State = state
+	return state, nil
+}
+
 // HaltHart halts the current hart.
 func (dbg *Debug) HaltHart() error {
 	_, err := dbg.halt()
@@ -252,6 +263,60 @@ func (dbg *Debug) ResumeHart() error {
 	return err
 }
 
+// StepHart single steps the current hart.
+func (dbg *Debug) StepHart(ie bool) error {
+	return dbg.step(ie)
+}
+
+// allHarts calls a hart function for each hart in a given state.
+// The current hart is restored afterwards.
+func (dbg *Debug) allHarts(state rv.HartState, f func() error) error {
+	id := dbg.hartid
+	for i := range dbg.hart {
+		_, err := dbg.SetCurrentHart(i)
+		if err != nil {
+			return err
+		}
+		s, err := dbg.GetHartState()
+		if err != nil {
+			return err
+		}
+		if s != state {
+			continue
+		}
+		err = f()
+		if err != nil {
+			return fmt.Errorf("hart%d: %v", i, err)
+		}
+	}
+	_, err := dbg.SetCurrentHart(id)
+	return err
+}
+
+// HaltAll halts all harts.
+// The 0.11 debug module halts harts with a per-hart debug interrupt,
+// so the harts are halted one at a time, back to back.
+func (dbg *Debug) HaltAll() (bool, error) {
+	return len(dbg.hart) <= 1, dbg.allHarts(rv.Running, dbg.HaltHart)
+}
+
+// ResumeAll resumes all harts.
+// The harts are resumed one at a time (see HaltAll).
+func (dbg *Debug) ResumeAll() (bool, error) {
+	return len(dbg.hart) <= 1, dbg.allHarts(rv.Halted, dbg.ResumeHart)
+}
+
+// Reset resets the system and re-examines the harts.
+func (dbg *Debug) Reset(halt bool) error {
+	id := dbg.hartid
+	err := dbg.reset(halt)
+	if err != nil {
+		return err
+	}
+	_, err = dbg.SetCurrentHart(id)
+	return err
+}
+
 //-----------------------------------------------------------------------------
 
 // GetPrompt returns a target prompt string.
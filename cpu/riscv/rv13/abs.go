@@ -10,6 +10,8 @@ package rv13
 
 import (
 	"fmt"
+
+	"github.com/deadsy/rvdbg/util"
 )
 
 //-----------------------------------------------------------------------------
@@ -193,3 +195,124 @@ func acWrCSR(dbg *Debug, reg, size uint, val uint64) error {
 }
 
 //-----------------------------------------------------------------------------
+// memory
+
+// acAddrOps returns the dmi operations to setup the address argument (arg1) for an access memory command.
+func (dbg *Debug) acAddrOps(addr uint) ([]dmiOp, error) {
+	mxlen := dbg.GetCurrentHart().MXLEN
+	switch mxlen {
+	case 32:
+		return []dmiOp{dmiWr(data1, uint32(addr))}, nil
+	case 64:
+		return []dmiOp{dmiWr(data2, uint32(addr)), dmiWr(data3, uint32(addr>>32))}, nil
+	}
+	return nil, fmt.Errorf("memory access with a %d-bit address is not supported", mxlen)
+}
+
+// acRdMem reads n x width-bit values from memory using abstract access memory commands.
+func acRdMem(dbg *Debug, width, addr, n uint) ([]uint, error) {
+	if width == 64 && dbg.GetCurrentHart().MXLEN < 64 {
+		return nil, fmt.Errorf("%d-bit memory reads are not supported", width)
+	}
+	ops, err := dbg.acAddrOps(addr)
+	if err != nil {
+		return nil, err
+	}
+	// read the first value and increment the address
+	cmd := cmdMemory(sizeMap[width], false, true, false)
+	ops = append(ops, dmiWr(command, cmd))
+	// dataX reads with autoexec re-run the command
+	rdData := func() {
+		if width == 64 {
+			ops = append(ops, dmiRd(data1))
+		}
+		ops = append(ops, dmiRd(data0))
+	}
+	if n > 1 {
+		if dbg.autoexecdata {
+			ops = append(ops, dmiWr(abstractauto, 1<<0))
+			for i := 0; i < int(n)-1; i++ {
+				rdData()
+			}
+			ops = append(ops, dmiWr(abstractauto, 0))
+		} else {
+			for i := 0; i < int(n)-1; i++ {
+				rdData()
+				ops = append(ops, dmiWr(command, cmd))
+			}
+		}
+	}
+	// read the final value
+	rdData()
+	// read the command status
+	ops = append(ops, dmiRd(abstractcs))
+	ops = append(ops, dmiEnd())
+	// run the operations
+	data, err := dbg.dmiOps(ops)
+	if err != nil {
+		return nil, err
+	}
+	// check the command status
+	err = dbg.checkError(cmdStatus(data[len(data)-1]))
+	if err != nil {
+		return nil, err
+	}
+	// return the data
+	val := make([]uint, n)
+	for i := range val {
+		if width == 64 {
+			val[i] = (uint(data[2*i]) << 32) | uint(data[2*i+1])
+		} else {
+			// mask 8/16-bit reads to the access width
+			val[i] = uint(data[i]) & util.Mask(width-1, 0)
+		}
+	}
+	return val, nil
+}
+
+// acWrMem writes n x width-bit values to memory using abstract access memory commands.
+func acWrMem(dbg *Debug, width, addr uint, val []uint) error {
+	if width == 64 && dbg.GetCurrentHart().MXLEN < 64 {
+		return fmt.Errorf("%d-bit memory writes are not supported", width)
+	}
+	ops, err := dbg.acAddrOps(addr)
+	if err != nil {
+		return err
+	}
+	cmd := cmdMemory(sizeMap[width], false, true, true)
+	wrData := func(v uint) {
+		if width == 64 {
+			ops = append(ops, dmiWr(data1, uint32(v>>32)))
+		}
+		ops = append(ops, dmiWr(data0, uint32(v)))
+	}
+	// write the first value and increment the address
+	wrData(val[0])
+	ops = append(ops, dmiWr(command, cmd))
+	if len(val) > 1 {
+		if dbg.autoexecdata {
+			// data0 writes with autoexec re-run the command
+			ops = append(ops, dmiWr(abstractauto, 1<<0))
+			for _, v := range val[1:] {
+				wrData(v)
+			}
+			ops = append(ops, dmiWr(abstractauto, 0))
+		} else {
+			for _, v := range val[1:] {
+				wrData(v)
+				ops = append(ops, dmiWr(command, cmd))
+			}
+		}
+	}
+	// read the command status
+	ops = append(ops, dmiRd(abstractcs))
+	ops = append(ops, dmiEnd())
+	// run the operations
+	data, err := dbg.dmiOps(ops)
+	if err != nil {
+		return err
+	}
+	return dbg.cmdWait(cmdStatus(data[0]), cmdTimeout)
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,173 @@
+//-----------------------------------------------------------------------------
+/*
+
+RISC-V Debugger 0.13
+
+Debug Module Authentication
+
+A debug module may require the debugger to authenticate before it can be
+used. The authentication protocol runs over the authdata register and is
+vendor specific. Targets provide an Authenticator for their scheme.
+
+*/
+//-----------------------------------------------------------------------------
+
+package rv13
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//-----------------------------------------------------------------------------
+
+// AuthData provides access to the authdata register.
+type AuthData interface {
+	RdAuthData() (uint32, error) // read authdata
+	WrAuthData(x uint32) error   // write authdata
+}
+
+// Authenticator implements a debug module authentication scheme.
+type Authenticator interface {
+	Authenticate(ad AuthData) error // run the authentication protocol
+}
+
+// ErrLocked is returned when the debug module requires authentication.
+var ErrLocked = errors.New("debug module is locked, authentication is required")
+
+//-----------------------------------------------------------------------------
+// dmstatus authentication bits
+
+const authenticated = (1 << 7)
+const authbusy = (1 << 6)
+
+// dmiAccess provides dmi register reads and writes.
+type dmiAccess interface {
+	rdDmi(addr uint) (uint32, error)
+	wrDmi(addr uint, data uint32) error
+}
+
+// authData implements the AuthData interface for a debug module.
+type authData struct {
+	dm dmiAccess
+}
+
+const authTimeout = 100 * time.Millisecond
+
+// wait waits for dmstatus.authbusy to be clear.
+func (ad *authData) wait() error {
+	t := time.Now().Add(authTimeout)
+	for {
+		x, err := ad.dm.rdDmi(dmstatus)
+		if err != nil {
+			return err
+		}
+		if x&authbusy == 0 {
+			return nil
+		}
+		if !t.After(time.Now()) {
+			return errors.New("timeout waiting for authbusy")
+		}
+		time.Sleep(1 * time.Millisecond)
+	}
+}
+
+// RdAuthData reads the authdata register.
+func (ad *authData) RdAuthData() (uint32, error) {
+	err := ad.wait()
+	if err != nil {
+		return 0, err
+	}
+	return ad.dm.rdDmi(authdata)
+}
+
+// WrAuthData writes the authdata register.
+func (ad *authData) WrAuthData(x uint32) error {
+	err := ad.wait()
+	if err != nil {
+		return err
+	}
+	return ad.dm.wrDmi(authdata, x)
+}
+
+// isAuthenticated returns true if the debug module has been authenticated.
+func isAuthenticated(dm dmiAccess) (bool, error) {
+	x, err := dm.rdDmi(dmstatus)
+	if err != nil {
+		return false, err
+	}
+	return x&authenticated != 0, nil
+}
+
+// authenticate authenticates the debug module (if required).
+func authenticate(dm dmiAccess, auth Authenticator) error {
+	ok, err := isAuthenticated(dm)
+	if err != nil || ok {
+		return err
+	}
+	if auth == nil {
+		return ErrLocked
+	}
+	ad := &authData{dm: dm}
+	err = auth.Authenticate(ad)
+	if err != nil {
+		return fmt.Errorf("authentication error: %v", err)
+	}
+	// wait for the debug module to process the last write
+	err = ad.wait()
+	if err != nil {
+		return err
+	}
+	ok, err = isAuthenticated(dm)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errors.New("authentication failed")
+	}
+	return nil
+}
+
+//-----------------------------------------------------------------------------
+// reference authenticator
+
+// PasswordAuth is a reference challenge/response authenticator.
+// For each password word the debug module provides a challenge in authdata
+// and the response is the challenge xor'ed with the password word.
+type PasswordAuth struct {
+	Password []uint32
+}
+
+// Authenticate runs the password authentication protocol.
+func (pa *PasswordAuth) Authenticate(ad AuthData) error {
+	for _, p := range pa.Password {
+		challenge, err := ad.RdAuthData()
+		if err != nil {
+			return err
+		}
+		err = ad.WrAuthData(challenge ^ p)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ParsePassword parses a comma separated list of 32-bit hex password words.
+func ParsePassword(s string) ([]uint32, error) {
+	pw := []uint32{}
+	for _, w := range strings.Split(s, ",") {
+		w = strings.TrimPrefix(strings.TrimSpace(w), "0x")
+		x, err := strconv.ParseUint(w, 16, 32)
+		if err != nil {
+			return nil, fmt.Errorf("bad password word \"%s\"", w)
+		}
+		pw = append(pw, uint32(x))
+	}
+	return pw, nil
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,125 @@
+//-----------------------------------------------------------------------------
+/*
+
+Debug module authentication test functions.
+
+*/
+//-----------------------------------------------------------------------------
+
+package rv13
+
+import (
+	"errors"
+	"math/rand"
+	"testing"
+)
+
+//-----------------------------------------------------------------------------
+
+// simDM is a simulated debug module implementing the PasswordAuth scheme.
+type simDM struct {
+	password  []uint32
+	idx       int    // index of the current password word
+	challenge uint32 // current challenge
+	auth      bool   // authenticated
+	busy      int    // number of dmstatus reads with authbusy set
+	fail      bool   // fail dmi accesses
+}
+
+func newSimDM(password []uint32) *simDM {
+	return &simDM{
+		password:  password,
+		challenge: rand.Uint32(),
+	}
+}
+
+func (dm *simDM) rdDmi(addr uint) (uint32, error) {
+	if dm.fail {
+		return 0, errors.New("dmi failure")
+	}
+	switch addr {
+	case dmstatus:
+		x := uint32(2) // version
+		if dm.auth {
+			x |= authenticated
+		}
+		if dm.busy > 0 {
+			x |= authbusy
+			dm.busy--
+		}
+		return x, nil
+	case authdata:
+		if dm.busy > 0 {
+			return 0, errors.New("authdata read with authbusy set")
+		}
+		return dm.challenge, nil
+	}
+	return 0, nil
+}
+
+func (dm *simDM) wrDmi(addr uint, data uint32) error {
+	if dm.fail {
+		return errors.New("dmi failure")
+	}
+	if addr != authdata {
+		return nil
+	}
+	if dm.busy > 0 {
+		return errors.New("authdata write with authbusy set")
+	}
+	// checking the response takes a while
+	dm.busy = 2
+	if data == dm.challenge^dm.password[dm.idx] {
+		dm.idx++
+		if dm.idx == len(dm.password) {
+			dm.auth = true
+		}
+	} else {
+		// start over
+		dm.idx = 0
+	}
+	dm.challenge = rand.Uint32()
+	return nil
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_Authenticate(t *testing.T) {
+	password := []uint32{0xdeadbeef, 0x01234567, 0xcafebabe}
+
+	test := []struct {
+		auth Authenticator
+		fail bool
+		err  error
+	}{
+		{&PasswordAuth{Password: password}, false, nil},
+		{&PasswordAuth{Password: []uint32{0xdeadbeef, 0x01234567, 0}}, false, errors.New("authentication failed")},
+		{&PasswordAuth{Password: password[:2]}, false, errors.New("authentication failed")},
+		{nil, false, ErrLocked},
+		{&PasswordAuth{Password: password}, true, errors.New("dmi failure")},
+	}
+
+	for i, v := range test {
+		dm := newSimDM(password)
+		dm.fail = v.fail
+		err := authenticate(dm, v.auth)
+		if (err == nil) != (v.err == nil) || (err != nil && err.Error() != v.err.Error()) {
+			t.Errorf("test %d: expected error %v, actual %v", i, v.err, err)
+		}
+		if (err == nil) != dm.auth {
+			t.Errorf("test %d: authenticated %t, error %v", i, dm.auth, err)
+		}
+	}
+}
+
+func Test_Authenticated(t *testing.T) {
+	// an unlocked debug module doesn't need an authenticator
+	dm := newSimDM(nil)
+	dm.auth = true
+	err := authenticate(dm, nil)
+	if err != nil {
+		t.Errorf("expected no error, actual %v", err)
+	}
+}
+
+//-----------------------------------------------------------------------------
@@ -12,9 +12,11 @@ package rv13
 
 import (
 	"fmt"
+	"time"
 
 	cli "github.com/deadsy/go-cli"
 	"github.com/deadsy/rvdbg/cpu/riscv/rv"
+	"github.com/deadsy/rvdbg/util"
 )
 
 //-----------------------------------------------------------------------------
@@ -55,8 +57,91 @@ var cmdInfo = cli.Leaf{
 	},
 }
 
+var cmdAuth = cli.Leaf{
+	Descr: "authenticate the debug modules",
+	F: func(c *cli.CLI, args []string) {
+		dbg := c.User.(target).GetRiscvDebug().(*Debug)
+		err := dbg.authenticateAll()
+		if err != nil {
+			c.User.Put(fmt.Sprintf("%v\n", err))
+			return
+		}
+		c.User.Put("debug modules are authenticated\n")
+	},
+}
+
+var benchHelp = []cli.Help{
+	{"<addr> [len]", "memory region"},
+	{"  addr", "address (hex)"},
+	{"  len", "length (hex), defaults to 0x8000"},
+	{"", "the region is read and then written back with the same data"},
+}
+
+const benchSize = 0x8000
+
+// rate returns a transfer rate string.
+func rate(n uint, t time.Duration) string {
+	return fmt.Sprintf("%.1f KiB/s", float64(n)/(util.KiB*t.Seconds()))
+}
+
+var cmdBench = cli.Leaf{
+	Descr: "benchmark memory read/write speed",
+	F: func(c *cli.CLI, args []string) {
+		dbg := c.User.(target).GetRiscvDebug().(*Debug)
+		err := cli.CheckArgc(args, []int{1, 2})
+		if err != nil {
+			c.User.Put(fmt.Sprintf("%s\n", err))
+			return
+		}
+		maxAddr := uint((1 << dbg.GetAddressSize()) - 1)
+		addr, err := cli.UintArg(args[0], [2]uint{0, maxAddr}, 16)
+		if err != nil {
+			c.User.Put(fmt.Sprintf("%s\n", err))
+			return
+		}
+		if addr&3 != 0 {
+			c.User.Put("address is not 32-bit aligned\n")
+			return
+		}
+		size := uint(benchSize)
+		if len(args) == 2 {
+			size, err = cli.UintArg(args[1], [2]uint{4, 0x1000000}, 16)
+			if err != nil {
+				c.User.Put(fmt.Sprintf("%s\n", err))
+				return
+			}
+		}
+		n := size >> 2
+		hi := dbg.hart[dbg.hartid]
+		// read
+		t0 := time.Now()
+		buf, err := dbg.RdMem(32, addr, n)
+		if err != nil {
+			c.User.Put(fmt.Sprintf("read error: %v\n", err))
+			return
+		}
+		rd := time.Since(t0)
+		// write back the same data
+		t0 = time.Now()
+		err = dbg.WrMem(32, addr, buf)
+		if err != nil {
+			c.User.Put(fmt.Sprintf("write error: %v\n", err))
+			return
+		}
+		wr := time.Since(t0)
+		s := [][]string{}
+		s = append(s, []string{"access", hi.memAccess})
+		s = append(s, []string{"size", util.MemSize(n << 2)})
+		s = append(s, []string{"read", rate(n<<2, rd)})
+		s = append(s, []string{"write", rate(n<<2, wr)})
+		c.User.Put(fmt.Sprintf("%s\n", cli.TableString(s, []int{0, 0}, 1)))
+	},
+}
+
 // Menu debug submenu items
 var Menu = cli.Menu{
+	{"auth", cmdAuth},
+	{"bench", cmdBench, benchHelp},
 	{"cache", cmdCache},
 	{"dmi", cmdDmi},
 	{"info", cmdInfo},
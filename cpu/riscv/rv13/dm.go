@@ -0,0 +1,261 @@
+//-----------------------------------------------------------------------------
+/*
+
+RISC-V Debugger 0.13
+
+Debug Module Functions
+
+A DMI bus can have several debug modules chained together with nextdm.
+Each debug module has its own set of harts and capabilities. The debugger
+works with the debug module for the currently selected hart, so DMI register
+accesses are offset by the base address of that debug module.
+
+*/
+//-----------------------------------------------------------------------------
+
+package rv13
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/deadsy/rvdbg/util"
+	"github.com/deadsy/rvdbg/util/log"
+)
+
+//-----------------------------------------------------------------------------
+
+// maxModules is the maximum number of debug modules on the nextdm chain.
+const maxModules = 16
+
+// debugModule stores the per debug module information.
+type debugModule struct {
+	index           int  // index on the nextdm chain
+	base            uint // dmi base address
+	progbufsize     uint // number of progbuf words implemented
+	datacount       uint // number of data words implemented
+	autoexecprogbuf bool // can we autoexec on progbufX access?
+	autoexecdata    bool // can we autoexec on dataX access?
+	sbasize         uint // width of system bus address (0 = no access)
+	sbaccess        uint // supported system bus access widths (sbcs.sbaccess128..8)
+	hartsellen      uint // hart select length 0..20
+	impebreak       uint // implicit ebreak in progbuf
+	hasel           bool // hart array mask is supported
+}
+
+// rows returns the debug module information as table rows.
+func (dm *debugModule) rows(prefix string) [][]string {
+	s := [][]string{}
+	s = append(s, []string{prefix + "dmi base", fmt.Sprintf("0x%x", dm.base)})
+	s = append(s, []string{prefix + "sbasize", fmt.Sprintf("%d bits", dm.sbasize)})
+	s = append(s, []string{prefix + "sbaccess", sbaccessString(dm.sbaccess)})
+	s = append(s, []string{prefix + "progbufsize", fmt.Sprintf("%d words", dm.progbufsize)})
+	s = append(s, []string{prefix + "datacount", fmt.Sprintf("%d words", dm.datacount)})
+	s = append(s, []string{prefix + "autoexecprogbuf", fmt.Sprintf("%t", dm.autoexecprogbuf)})
+	s = append(s, []string{prefix + "autoexecdata", fmt.Sprintf("%t", dm.autoexecdata)})
+	s = append(s, []string{prefix + "hasel", fmt.Sprintf("%t", dm.hasel)})
+	return s
+}
+
+// selectModule makes a debug module the current debug module.
+func (dbg *Debug) selectModule(dm *debugModule) {
+	dbg.debugModule = dm
+}
+
+//-----------------------------------------------------------------------------
+
+// examineModule sets up the debug module at a dmi base address and adds its harts.
+func (dbg *Debug) examineModule(base uint) error {
+	dm := &debugModule{
+		index: len(dbg.modules),
+		base:  base,
+	}
+	dbg.selectModule(dm)
+	log.Info.Printf("dm%d: dmi base 0x%x", dm.index, dm.base)
+
+	// make the dmi active
+	err := dbg.wrDmi(dmcontrol, 0)
+	if err != nil {
+		return err
+	}
+	err = dbg.wrDmi(dmcontrol, dmactive)
+	if err != nil {
+		return err
+	}
+
+	// check the version
+	x, err := dbg.rdDmi(dmstatus)
+	if err != nil {
+		return err
+	}
+	if util.Bits(uint(x), 3, 0) != 2 {
+		return fmt.Errorf("dm%d: unknown dmstatus version", dm.index)
+	}
+
+	// authenticate the debug module before using it
+	err = authenticate(dbg, dbg.auth)
+	if err != nil {
+		return fmt.Errorf("dm%d: %v", dm.index, err)
+	}
+
+	// write all-ones to hartsel
+	err = dbg.selectHart((1 << 20) - 1)
+	if err != nil {
+		return err
+	}
+
+	// read back dmcontrol
+	x, err = dbg.rdDmi(dmcontrol)
+	if err != nil {
+		return err
+	}
+	// check dmi is active
+	if x&dmactive == 0 {
+		return fmt.Errorf("dm%d: dmi is not active", dm.index)
+	}
+	// work out hartsellen
+	hartsel := getHartSelect(x)
+	for hartsel&1 != 0 {
+		dm.hartsellen++
+		hartsel >>= 1
+	}
+	log.Info.Printf("dm%d: hartsellen %d", dm.index, dm.hartsellen)
+
+	// can we select groups of harts?
+	dm.hasel, err = dbg.probeHasel()
+	if err != nil {
+		return err
+	}
+	log.Info.Printf("dm%d: hasel %t", dm.index, dm.hasel)
+
+	// dmstatus fields are read after authentication
+	x, err = dbg.rdDmi(dmstatus)
+	if err != nil {
+		return err
+	}
+	// implicit ebreak after progbuf
+	dm.impebreak = util.Bit(uint(x), 22)
+
+	// work out the system bus address size
+	x, err = dbg.rdDmi(sbcs)
+	if err != nil {
+		return err
+	}
+	if util.Bits(uint(x), 31, 29) == 1 {
+		dm.sbasize = util.Bits(uint(x), 11, 5)
+		dm.sbaccess = util.Bits(uint(x), 4, 0)
+	}
+	log.Info.Printf("dm%d: sbasize %d sbaccess 0x%x", dm.index, dm.sbasize, dm.sbaccess)
+
+	// work out how many program and data words we have
+	x, err = dbg.rdDmi(abstractcs)
+	if err != nil {
+		return err
+	}
+	dm.progbufsize = util.Bits(uint(x), 28, 24)
+	dm.datacount = util.Bits(uint(x), 3, 0)
+
+	// check progbuf/impebreak consistency
+	if dm.progbufsize == 1 && dm.impebreak != 1 {
+		return fmt.Errorf("dm%d: progbufsize == 1 and impebreak != 1", dm.index)
+	}
+
+	// work out if we can autoexec on progbuf/data access
+	err = dbg.wrDmi(abstractauto, 0xffffffff)
+	if err != nil {
+		return err
+	}
+	x, err = dbg.rdDmi(abstractauto)
+	if err != nil {
+		return err
+	}
+	if util.Bits(uint(x), 31, 16) == ((1 << dm.progbufsize) - 1) {
+		dm.autoexecprogbuf = true
+	}
+	if util.Bits(uint(x), 11, 0) == ((1 << dm.datacount) - 1) {
+		dm.autoexecdata = true
+	}
+	// turn off autoexec
+	err = dbg.wrDmi(abstractauto, 0)
+	if err != nil {
+		return err
+	}
+
+	log.Info.Printf("dm%d: progbufsize %d impebreak %d autoexecprogbuf %t", dm.index, dm.progbufsize, dm.impebreak, dm.autoexecprogbuf)
+	log.Info.Printf("dm%d: datacount %d autoexecdata %t", dm.index, dm.datacount, dm.autoexecdata)
+
+	// clear any pending command errors
+	err = dbg.cmdErrorClr()
+	if err != nil {
+		return err
+	}
+
+	// enumerate the harts
+	n := 0
+	maxHarts := 1 << dm.hartsellen
+	for hartsel := 0; hartsel < maxHarts; hartsel++ {
+		// select the hart
+		err := dbg.selectHart(hartsel)
+		if err != nil {
+			return err
+		}
+		// get the hart status
+		x, err = dbg.rdDmi(dmstatus)
+		if err != nil {
+			return err
+		}
+		if x&anynonexistent != 0 {
+			// this hart does not exist - we're done
+			break
+		}
+		// add a hart to the list
+		dbg.hart = append(dbg.hart, dbg.newHart(len(dbg.hart), dm, hartsel))
+		n++
+	}
+	log.Info.Printf("dm%d: %d hart(s) found", dm.index, n)
+
+	dbg.modules = append(dbg.modules, dm)
+	return nil
+}
+
+// examineModules walks the nextdm chain and examines each debug module.
+func (dbg *Debug) examineModules() error {
+	base := uint(0)
+	for {
+		err := dbg.examineModule(base)
+		if err != nil {
+			return err
+		}
+		x, err := dbg.rdDmi(nextdm)
+		if err != nil {
+			return err
+		}
+		if x == 0 {
+			// end of the chain
+			return nil
+		}
+		if uint(x) >= (1 << dbg.abits) {
+			return fmt.Errorf("nextdm 0x%x is beyond the %d-bit dmi address space", x, dbg.abits)
+		}
+		if len(dbg.modules) == maxModules {
+			return errors.New("too many debug modules on the nextdm chain")
+		}
+		base = uint(x)
+	}
+}
+
+// authenticateAll authenticates all of the debug modules.
+func (dbg *Debug) authenticateAll() error {
+	dm := dbg.debugModule
+	defer dbg.selectModule(dm)
+	for _, m := range dbg.modules {
+		dbg.selectModule(m)
+		err := authenticate(dbg, dbg.auth)
+		if err != nil {
+			return fmt.Errorf("dm%d: %v", m.index, err)
+		}
+	}
+	return nil
+}
+
+//-----------------------------------------------------------------------------
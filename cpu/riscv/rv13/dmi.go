@@ -229,6 +229,8 @@ func newDMI() *soc.Device {
 const haltreq = (1 << 31)
 const resumereq = (1 << 30)
 const ackhavereset = (1 << 28)
+const setresethaltreq = (1 << 3)
+const clrresethaltreq = (1 << 2)
 const hartsello = ((1 << 10) - 1) << 16
 const hartselhi = ((1 << 10) - 1) << 6
 const ndmreset = (1 << 1)
@@ -283,6 +285,7 @@ func (dbg *Debug) selectHart(id int) error {
 //-----------------------------------------------------------------------------
 // DM status
 
+const allhavereset = (1 << 19)
 const anyhavereset = (1 << 18)
 const allresumeack = (1 << 17)
 const anyresumeack = (1 << 16)
@@ -290,6 +293,7 @@ const anynonexistent = (1 << 14)
 const anyunavail = (1 << 12)
 const allrunning = (1 << 11)
 const allhalted = (1 << 9)
+const hasresethaltreq = (1 << 5)
 
 // checkStatus checks the dmstatus register for a flag.
 func (dbg *Debug) checkStatus(flag uint32) (bool, error) {
@@ -335,7 +339,27 @@ func (x dmiOp) isRead() bool {
 	return (x & opMask) == opRd
 }
 
+// dmiRetries is the number of times a failed dmi operation is retried.
+const dmiRetries = 3
+
+// dmiRecover clears a dmi error condition.
+func (dbg *Debug) dmiRecover(hard bool) error {
+	x := uint(dmireset)
+	if hard {
+		// last resort, this aborts any outstanding dmi transaction
+		x |= dmihardreset
+		dbg.stats.hardresets++
+	}
+	err := dbg.wrDtmcs(x)
+	if err != nil {
+		return err
+	}
+	// re-select dmi
+	return dbg.wrIR(irDmi)
+}
+
 // dmiOps runs a set of dmi operations and returns any read data.
+// Each scan returns the result of the previous operation.
 func (dbg *Debug) dmiOps(ops []dmiOp) ([]uint32, error) {
 	data := []uint32{}
 
@@ -345,42 +369,69 @@ func (dbg *Debug) dmiOps(ops []dmiOp) ([]uint32, error) {
 		return nil, err
 	}
 
-	read := false
+	retries := 0
+	discard := false
 	for i := 0; i < len(ops); i++ {
 		op := ops[i]
+		if op&opMask != opIgnore {
+			// offset the address to the current debug module
+			op += dmiOp(dbg.base << 34)
+		}
 		// run the operation
 		tdo, err := dbg.dev.RdWrDR(bitstr.FromUint(uint(op), dbg.drDmiLength), dbg.idle)
 		if err != nil {
 			return nil, err
 		}
+		dbg.stats.ops++
 		x := tdo.Split([]int{dbg.drDmiLength})[0]
 		// check the result
 		result := x & opMask
-		if result != opOk {
-			// clear error condition
-			dbg.wrDtmcs(dmireset)
-			// re-select dmi
-			dbg.wrIR(irDmi)
-			if result == opBusy {
-				// auto-adjust timing
-				log.Info.Printf("increment idle timing %d->%d cycles", dbg.idle, dbg.idle+1)
-				dbg.idle++
-				if dbg.idle > jtag.MaxIdle {
-					return nil, fmt.Errorf("dmi operation error %d", result)
-				}
-				// redo the operation
-				i--
-				continue
-			} else {
+		switch result {
+		case opOk:
+			// get the read data for the previous operation
+			if i > 0 && ops[i-1].isRead() && !discard {
+				data = append(data, uint32((x>>2)&util.Mask32))
+			}
+			discard = false
+			continue
+		case opBusy:
+			// The previous operation was still in progress and this one was ignored.
+			dbg.stats.busy++
+			if dbg.idle >= jtag.MaxIdle {
+				dbg.dmiRecover(true)
+				return nil, fmt.Errorf("dmi operation error: busy with %d idle cycles", dbg.idle)
+			}
+			// auto-adjust timing, the new idle value is kept for this session
+			log.Info.Printf("increment idle timing %d->%d cycles", dbg.idle, dbg.idle+1)
+			dbg.idle++
+			err := dbg.dmiRecover(false)
+			if err != nil {
+				return nil, err
+			}
+			// redo the operation, the scan returns the previous result
+			i--
+		default:
+			// The previous operation failed, or the scan returned garbage.
+			dbg.stats.fail++
+			if retries >= dmiRetries {
+				dbg.dmiRecover(true)
 				return nil, fmt.Errorf("dmi operation error %d", result)
 			}
+			retries++
+			dbg.stats.retries++
+			err := dbg.dmiRecover(false)
+			if err != nil {
+				return nil, err
+			}
+			// redo the previous operation (if any) and this operation
+			if i > 0 {
+				i -= 2
+				// the first scan returns a stale result
+				discard = true
+			} else {
+				i--
+			}
 		}
-		// get the read data
-		if read {
-			data = append(data, uint32((x>>2)&util.Mask32))
-		}
-		// setup the next read
-		read = op.isRead()
 	}
 	return data, nil
 }
@@ -418,6 +469,10 @@ func (ce cmdErr) String() string {
 	}[ce]
 }
 
+func (ce cmdErr) Error() string {
+	return fmt.Sprintf("error: %s(%d)", ce.String(), uint(ce))
+}
+
 // getError returns the error field of the command status.
 func (cs cmdStatus) getError() cmdErr {
 	return cmdErr(util.Bits(uint(cs), 10, 8))
@@ -504,7 +559,7 @@ func (dbg *Debug) checkError(cs cmdStatus) error {
 	if err != nil {
 		return err
 	}
-	return fmt.Errorf("error: %s(%d)", ce, ce)
+	return ce
 }
 
 const cmdTimeout = 10 * time.Millisecond
@@ -524,7 +579,7 @@ func (dbg *Debug) cmdWait(cs cmdStatus, to time.Duration) error {
 				if err != nil {
 					return err
 				}
-				return fmt.Errorf("error: %s(%d)", ce, ce)
+				return ce
 			}
 			return nil
 		}
@@ -0,0 +1,220 @@
+//-----------------------------------------------------------------------------
+/*
+
+RISC-V Debugger 0.13
+
+Hart Group Functions
+
+The hart array mask (hawindowsel/hawindow) selects a group of harts in
+addition to the hart selected by hartsel. With dmcontrol.hasel set a
+halt or resume request is applied to all harts in the group at once.
+The hart array mask is per debug module, so requests are grouped by module.
+
+*/
+//-----------------------------------------------------------------------------
+
+package rv13
+
+import (
+	"errors"
+	"time"
+
+	"github.com/deadsy/rvdbg/cpu/riscv/rv"
+	"github.com/deadsy/rvdbg/util"
+)
+
+//-----------------------------------------------------------------------------
+
+const hasel = (1 << 26)
+
+// probeHasel returns true if the debug module supports the hart array mask.
+func (dbg *Debug) probeHasel() (bool, error) {
+	err := dbg.setDmi(dmcontrol, hasel)
+	if err != nil {
+		return false, err
+	}
+	x, err := dbg.rdDmi(dmcontrol)
+	if err != nil {
+		return false, err
+	}
+	err = dbg.clrDmi(dmcontrol, hasel)
+	if err != nil {
+		return false, err
+	}
+	return x&hasel != 0, nil
+}
+
+// setHartMask sets the hart array mask to select the harts in a list.
+// The harts must all belong to the current debug module.
+// Every window covering the harts of the module is written, so bits left
+// set by a previous mask are cleared.
+func (dbg *Debug) setHartMask(ids []int) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	dm := dbg.hart[ids[0]].dm
+	n := 0
+	for _, hi := range dbg.hart {
+		if hi.dm == dm && hi.hartsel >= n {
+			n = hi.hartsel + 1
+		}
+	}
+	mask := make([]uint32, (n+31)/32)
+	for _, id := range ids {
+		hartsel := dbg.hart[id].hartsel
+		mask[hartsel>>5] |= 1 << uint(hartsel&31)
+	}
+	for i := range mask {
+		err := dbg.wrDmi(hawindowsel, uint32(i))
+		if err != nil {
+			return err
+		}
+		err = dbg.wrDmi(hawindow, mask[i])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// groupRequest makes a halt/resume request of a group of harts and waits
+// for the dmstatus flag to be set for all of them.
+// The harts must all belong to the same debug module.
+func (dbg *Debug) groupRequest(ids []int, req, flag uint32, timeout time.Duration) (bool, error) {
+	if len(ids) == 0 {
+		return true, nil
+	}
+	// hartsel must also be a member of the group
+	_, err := dbg.SetCurrentHart(ids[0])
+	if err != nil {
+		return false, err
+	}
+	err = dbg.setHartMask(ids)
+	if err != nil {
+		return false, err
+	}
+	// make the request
+	err = dbg.setDmi(dmcontrol, hasel|req)
+	if err != nil {
+		return false, err
+	}
+	// wait for the group to respond
+	ok, err := dbg.waitStatus(flag, timeout)
+	if err != nil {
+		return false, err
+	}
+	// clear the request
+	err = dbg.clrDmi(dmcontrol, hasel|req)
+	if err != nil {
+		return false, err
+	}
+	return ok, nil
+}
+
+// groupState returns the harts in a given state.
+func (dbg *Debug) groupState(state rv.HartState) ([]int, error) {
+	ids := []int{}
+	for i, hi := range dbg.hart {
+		_, err := dbg.SetCurrentHart(i)
+		if err != nil {
+			return nil, err
+		}
+		halted, err := dbg.isHalted()
+		if err != nil {
+			return nil, err
+		}
+		hi.info.State = []rv.HartState{rv.Running, rv.Halted}[util.BoolToInt(halted)]
+		if hi.info.State == state {
+			ids = append(ids, i)
+		}
+	}
+	return ids, nil
+}
+
+// groupByModule splits a list of harts by debug module.
+func (dbg *Debug) groupByModule(ids []int) [][]int {
+	groups := make([][]int, len(dbg.modules))
+	for _, id := range ids {
+		i := dbg.hart[id].dm.index
+		groups[i] = append(groups[i], id)
+	}
+	return groups
+}
+
+// groupRun makes a halt/resume request of a list of harts.
+// A group request is used for debug modules with a hart array mask,
+// otherwise the single hart function is called for each hart.
+// It returns true if the harts were run with a single request.
+func (dbg *Debug) groupRun(ids []int, req, flag uint32, timeout time.Duration, single func() (bool, error)) (bool, bool, error) {
+	together := true
+	requests := 0
+	for i, group := range dbg.groupByModule(ids) {
+		if len(group) == 0 {
+			continue
+		}
+		if !dbg.modules[i].hasel {
+			// one hart at a time
+			for _, id := range group {
+				_, err := dbg.SetCurrentHart(id)
+				if err != nil {
+					return false, false, err
+				}
+				_, err = single()
+				if err != nil {
+					return false, false, err
+				}
+			}
+			together = together && len(group) == 1
+			requests++
+			continue
+		}
+		ok, err := dbg.groupRequest(group, req, flag, timeout)
+		if err != nil || !ok {
+			return false, false, err
+		}
+		requests++
+	}
+	return true, together && requests <= 1, nil
+}
+
+// haltAll halts all harts.
+// It returns true if the harts were halted together.
+func (dbg *Debug) haltAll() (bool, error) {
+	ids, err := dbg.groupState(rv.Running)
+	if err != nil {
+		return false, err
+	}
+	ok, together, err := dbg.groupRun(ids, haltreq, allhalted, haltTimeout, dbg.halt)
+	if err != nil {
+		return false, err
+	}
+	if !ok {
+		return false, errors.New("unable to halt all harts")
+	}
+	for _, id := range ids {
+		dbg.hart[id].info.State = rv.Halted
+	}
+	return together, nil
+}
+
+// resumeAll resumes all harts.
+// It returns true if the harts were resumed together.
+func (dbg *Debug) resumeAll() (bool, error) {
+	ids, err := dbg.groupState(rv.Halted)
+	if err != nil {
+		return false, err
+	}
+	ok, together, err := dbg.groupRun(ids, resumereq, allresumeack, resumeTimeout, dbg.resume)
+	if err != nil {
+		return false, err
+	}
+	if !ok {
+		return false, errors.New("unable to resume all harts")
+	}
+	for _, id := range ids {
+		dbg.hart[id].info.State = rv.Running
+	}
+	return together, nil
+}
+
+//-----------------------------------------------------------------------------
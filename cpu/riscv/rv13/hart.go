@@ -123,6 +123,76 @@ func (dbg *Debug) resume() (bool, error) {
 	return false, nil
 }
 
+//-----------------------------------------------------------------------------
+// single step a hart
+
+// dcsr bits
+const (
+	dcsrStepie = (1 << 11)
+	dcsrStep   = (1 << 2)
+)
+
+const stepTimeout = 5 * time.Millisecond
+
+// step the current hart by a single instruction.
+func (dbg *Debug) step(ie bool) (err error) {
+	halted, err := dbg.isHalted()
+	if err != nil {
+		return err
+	}
+	if !halted {
+		return fmt.Errorf("hart%d is not halted", dbg.hartid)
+	}
+	// set dcsr.step (and dcsr.stepie)
+	dcsr, err := dbg.RdCSR(rv.DCSR, 0)
+	if err != nil {
+		return err
+	}
+	x := dcsr | dcsrStep
+	if ie {
+		x |= dcsrStepie
+	} else {
+		x &^= dcsrStepie
+	}
+	err = dbg.WrCSR(rv.DCSR, 0, x)
+	if err != nil {
+		return err
+	}
+	// restore dcsr.step and dcsr.stepie on all exit paths
+	defer func() {
+		rerr := dbg.WrCSR(rv.DCSR, 0, dcsr&^dcsrStep)
+		if err == nil {
+			err = rerr
+		}
+	}()
+	// resume the hart, it will halt after a single instruction
+	_, err = dbg.resume()
+	if err != nil {
+		return err
+	}
+	// wait for the hart to halt
+	t := time.Now().Add(stepTimeout)
+	for t.After(time.Now()) {
+		halted, err = dbg.isHalted()
+		if err != nil {
+			return err
+		}
+		if halted {
+			break
+		}
+		time.Sleep(1 * time.Millisecond)
+	}
+	// did we timeout?
+	if !halted {
+		_, err := dbg.halt()
+		if err != nil {
+			return err
+		}
+		return fmt.Errorf("hart%d did not halt after a single step", dbg.hartid)
+	}
+	return nil
+}
+
 //-----------------------------------------------------------------------------
 // access probing- setup pointers to access functions
 
@@ -157,21 +227,101 @@ func (hi *hartInfo) probeCSR() error {
 	return errors.New("unable to determine CSR access mode")
 }
 
+// memPath is a method for memory access using the hart.
+type memPath struct {
+	name string
+	rd   rdMemFunc
+	wr   wrMemFunc
+}
+
+// memProbeReads is the number of reads used to time a memory access path.
+const memProbeReads = 8
+
+// probe checks that a memory access path can read and write memory.
+// The write test writes back the value read at the address.
+// It returns the time taken for the timing reads and the test results.
+func (mp *memPath) probe(dbg *Debug, rdAddr, wrAddr uint) (time.Duration, bool, bool) {
+	t := time.Now()
+	for i := 0; i < memProbeReads; i++ {
+		_, err := mp.rd(dbg, 32, rdAddr, 1)
+		if err != nil {
+			return 0, false, false
+		}
+	}
+	elapsed := time.Since(t)
+	x, err := mp.rd(dbg, 32, wrAddr, 1)
+	if err != nil {
+		return elapsed, true, false
+	}
+	err = mp.wr(dbg, 32, wrAddr, x)
+	return elapsed, true, err == nil
+}
+
 // probeMemory works out how we can access memory.
+// The hart must be halted and MXLEN/DXLEN must be known.
 func (hi *hartInfo) probeMemory() error {
+	dbg := hi.dbg
+	// Reads are tested at the pc. Writes are tested on the stack
+	// because the pc is often in flash.
+	pc, err := dbg.RdCSR(rv.DPC, 0)
+	if err != nil {
+		return err
+	}
+	sp, err := dbg.RdGPR(rv.RegSp, 0)
+	if err != nil {
+		return err
+	}
+	rdAddr := uint(pc) &^ 3
+	wrAddr := uint(sp) &^ 3
+	if wrAddr == 0 {
+		wrAddr = rdAddr
+	}
+	paths := []*memPath{}
+	// abstract access memory commands
+	paths = append(paths, &memPath{"abstract command", acRdMem, acWrMem})
 	// We need 2 instructions + ebreak to r/w memory buffers.
-	supported := false
-	if hi.dbg.progbufsize >= 3 {
-		supported = true
+	if dbg.progbufsize >= 3 || (dbg.progbufsize == 2 && dbg.impebreak != 0) {
+		paths = append(paths, &memPath{"program buffer", pbRdMem, pbWrMem})
+	}
+	// Use the fastest path that works. If no path passes the write test
+	// (the stack pointer isn't setup after a reset) use the fastest reader.
+	var best *memPath
+	var bestTime time.Duration
+	bestWr := false
+	for _, mp := range paths {
+		t, rdOk, wrOk := mp.probe(dbg, rdAddr, wrAddr)
+		if !rdOk {
+			log.Info.Printf("hart%d: %s memory access is not supported", hi.info.ID, mp.name)
+			continue
+		}
+		log.Info.Printf("hart%d: %s memory access %v (write test %t)", hi.info.ID, mp.name, t, wrOk)
+		if best == nil || (wrOk && !bestWr) || (wrOk == bestWr && t < bestTime) {
+			best = mp
+			bestTime = t
+			bestWr = wrOk
+		}
 	}
-	if hi.dbg.progbufsize == 2 && hi.dbg.impebreak != 0 {
-		supported = true
+	if best != nil {
+		hi.rdMemHart = best.rd
+		hi.wrMemHart = best.wr
+		hi.memAccess = best.name
+	}
+	// Prefer the system bus, it works with the hart running.
+	if dbg.sbAccessSupported(32) {
+		hi.rdMem = sbRdMem
+		hi.wrMem = sbWrMem
+		if hi.memAccess != "" {
+			hi.memAccess = fmt.Sprintf("system bus (%s)", hi.memAccess)
+		} else {
+			hi.memAccess = "system bus"
+		}
+		return nil
 	}
-	if !supported {
+	if hi.rdMemHart == nil {
 		return errors.New("unable to support memory access")
 	}
-	hi.rdMem = pbRdMem
-	hi.wrMem = pbWrMem
+	hi.rdMem = hi.rdMemHart
+	hi.wrMem = hi.wrMemHart
 	return nil
 }
 
@@ -188,16 +338,7 @@ func (dbg *Debug) probeAccess() error {
 		return err
 	}
 	// CSRs
-	err = hi.probeCSR()
-	if err != nil {
-		return err
-	}
-	// Memory
-	err = hi.probeMemory()
-	if err != nil {
-		return err
-	}
-	return nil
+	return hi.probeCSR()
 }
 
 //-----------------------------------------------------------------------------
@@ -256,25 +397,31 @@ type wrMemFunc func(dbg *Debug, width, addr uint, val []uint) error
 
 // hartInfo stores generic/rv13 hart information.
 type hartInfo struct {
-	dbg        *Debug      // pointer back to parent debugger
-	info       rv.HartInfo // generic information
-	nscratch   uint        // number of dscratch registers
-	datasize   uint        // number of data registers in csr/memory
-	dataaccess uint        // data registers in csr(0)/memory(1)
-	dataaddr   uint        // csr/memory address
-	rdGPR      rdRegFunc   // read GPR function
-	rdFPR      rdRegFunc   // read FPR function
-	rdCSR      rdRegFunc   // read CSR function
-	wrGPR      wrRegFunc   // write GPR function
-	wrFPR      wrRegFunc   // write FPR function
-	wrCSR      wrRegFunc   // write CSR function
-	rdMem      rdMemFunc   // read memory buffer
-	wrMem      wrMemFunc   // write memory buffer
+	dbg        *Debug       // pointer back to parent debugger
+	dm         *debugModule // debug module for this hart
+	hartsel    int          // hart select value within the debug module
+	info       rv.HartInfo  // generic information
+	nscratch   uint         // number of dscratch registers
+	datasize   uint         // number of data registers in csr/memory
+	dataaccess uint         // data registers in csr(0)/memory(1)
+	dataaddr   uint         // csr/memory address
+	rdGPR      rdRegFunc    // read GPR function
+	rdFPR      rdRegFunc    // read FPR function
+	rdCSR      rdRegFunc    // read CSR function
+	wrGPR      wrRegFunc    // write GPR function
+	wrFPR      wrRegFunc    // write FPR function
+	wrCSR      wrRegFunc    // write CSR function
+	rdMem      rdMemFunc    // read memory buffer
+	wrMem      wrMemFunc    // write memory buffer
+	rdMemHart  rdMemFunc    // read memory buffer using the hart (nil == not supported)
+	wrMemHart  wrMemFunc    // write memory buffer using the hart (nil == not supported)
+	memAccess  string       // memory access method
 }
 
 func (hi *hartInfo) String() string {
 	s := []string{}
 	s = append(s, fmt.Sprintf("%s", &hi.info))
+	s = append(s, fmt.Sprintf("dm%d hartsel %d", hi.dm.index, hi.hartsel))
 	s = append(s, fmt.Sprintf("nscratch %d words", hi.nscratch))
 	s = append(s, fmt.Sprintf("datasize %d %s", hi.datasize, []string{"csr", "words"}[hi.dataaccess]))
 	s = append(s, fmt.Sprintf("dataaccess %s(%d)", []string{"csr", "memory"}[hi.dataaccess], hi.dataaccess))
@@ -376,6 +523,13 @@ func (hi *hartInfo) examine() error {
 		return err
 	}
 
+	// probe the memory access method
+	err = hi.probeMemory()
+	if err != nil {
+		return err
+	}
+	log.Info.Printf("hart%d: memory access %s", hi.info.ID, hi.memAccess)
+
 	// get the FLEN value
 	hi.info.FLEN, err = dbg.getFLEN()
 	if err != nil {
@@ -398,6 +552,26 @@ func (hi *hartInfo) examine() error {
 		log.Error.Printf("hart%d: misa has 128-bit floating point but FLEN < 128", hi.info.ID)
 	}
 
+	// get the vector register length
+	if rv.CheckExtMISA(hi.info.MISA, 'v') {
+		// vlenb is illegal with mstatus.vs == off
+		mstatus, err := rv.EnableVector(dbg)
+		if err != nil {
+			return err
+		}
+		vlenb, err := dbg.RdCSR(rv.VLENB, 0)
+		if err != nil {
+			log.Error.Printf("hart%d: misa has vector support but vlenb is not readable", hi.info.ID)
+		} else {
+			hi.info.VLENB = uint(vlenb)
+			log.Info.Printf("hart%d: VLENB %d", hi.info.ID, hi.info.VLENB)
+		}
+		err = dbg.WrCSR(rv.MSTATUS, 0, mstatus)
+		if err != nil {
+			return err
+		}
+	}
+
 	// get the hart id per the CSR
 	mhartid, err := dbg.RdCSR(rv.MHARTID, 0)
 	if err != nil {
@@ -444,9 +618,11 @@ func (hi *hartInfo) examine() error {
 }
 
 // newHart creates a hart info structure.
-func (dbg *Debug) newHart(id int) *hartInfo {
+func (dbg *Debug) newHart(id int, dm *debugModule, hartsel int) *hartInfo {
 	hi := &hartInfo{
-		dbg: dbg,
+		dbg:     dbg,
+		dm:      dm,
+		hartsel: hartsel,
 	}
 	hi.info.ID = id
 	hi.info.Nregs = 32
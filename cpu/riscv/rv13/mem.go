@@ -10,7 +10,10 @@ Implements the mem.Driver interface methods.
 package rv13
 
 import (
+	"errors"
 	"fmt"
+
+	"github.com/deadsy/rvdbg/cpu/riscv/rv"
 )
 
 //-----------------------------------------------------------------------------
@@ -45,3 +48,41 @@ func (dbg *Debug) WrMem(width, addr uint, val []uint) error {
 }
 
 //-----------------------------------------------------------------------------
+
+// confstrptrvalid is set in dmstatus when confstrptr0-3 hold a valid address.
+const confstrptrvalid = (1 << 4)
+
+// FenceI runs a fence.i on the current hart so instruction fetches see
+// memory written by the debugger (software breakpoints, loaded code).
+func (dbg *Debug) FenceI() error {
+	if dbg.progbufsize == 0 || (dbg.progbufsize == 1 && dbg.impebreak == 0) {
+		// no program buffer, the debug module must keep the caches coherent
+		return nil
+	}
+	pb := dbg.newProgramBuffer(2)
+	pb[0] = rv.InsFENCEI()
+	return dbg.pbExec(pb)
+}
+
+// GetConfigAddress returns the address of the configuration string/dtb.
+func (dbg *Debug) GetConfigAddress() (uint, error) {
+	x, err := dbg.rdDmi(dmstatus)
+	if err != nil {
+		return 0, err
+	}
+	if x&confstrptrvalid == 0 {
+		return 0, errors.New("configuration string pointer is not valid")
+	}
+	ops := []dmiOp{
+		dmiRd(confstrptr0),
+		dmiRd(confstrptr1),
+		dmiEnd(),
+	}
+	data, err := dbg.dmiOps(ops)
+	if err != nil {
+		return 0, err
+	}
+	return (uint(data[1]) << 32) | uint(data[0]), nil
+}
+
+//-----------------------------------------------------------------------------
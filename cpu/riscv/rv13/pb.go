@@ -35,6 +35,27 @@ func pbOps(pb []uint32, n int) []dmiOp {
 	return ops
 }
 
+//-----------------------------------------------------------------------------
+
+// pbExec runs the program buffer without a register transfer.
+func (dbg *Debug) pbExec(pb []uint32) error {
+	// build the operations buffer
+	ops := pbOps(pb, 3)
+	// postexec
+	ops = append(ops, dmiWr(command, cmdRegister(0, 0, cmdPostExec)))
+	// read the command status
+	ops = append(ops, dmiRd(abstractcs))
+	// done
+	ops = append(ops, dmiEnd())
+	// run the operations
+	data, err := dbg.dmiOps(ops)
+	if err != nil {
+		return err
+	}
+	// wait for command completion
+	return dbg.cmdWait(cmdStatus(data[0]), cmdTimeout)
+}
+
 //-----------------------------------------------------------------------------
 // program buffer read operations
 
@@ -147,17 +168,25 @@ func (dbg *Debug) pbRdMemRV32(addr, n uint, pb []uint32) ([]uint32, error) {
 	if n == 1 {
 		// transfer s1 to data0
 		ops = append(ops, dmiWr(command, cmdRegister(regGPR(rv.RegS1), size32, cmdRead)))
-	} else {
+	} else if dbg.autoexecdata {
 		// transfer s1 to data0 and then postexec to get the next value in s1
 		ops = append(ops, dmiWr(command, cmdRegister(regGPR(rv.RegS1), size32, cmdRead|cmdPostExec)))
 		// turn on autoexec for data0
 		ops = append(ops, dmiWr(abstractauto, 1<<0))
-		// do n-1 data reads
+		// do n-1 data reads, each read re-runs the command
 		for i := 0; i < int(n)-1; i++ {
 			ops = append(ops, dmiRd(data0))
 		}
 		// turn off autoexec
 		ops = append(ops, dmiWr(abstractauto, 0))
+	} else {
+		// no autoexec, run the command for each value
+		for i := 0; i < int(n)-1; i++ {
+			ops = append(ops, dmiWr(command, cmdRegister(regGPR(rv.RegS1), size32, cmdRead|cmdPostExec)))
+			ops = append(ops, dmiRd(data0))
+		}
+		// transfer the final s1 to data0
+		ops = append(ops, dmiWr(command, cmdRegister(regGPR(rv.RegS1), size32, cmdRead)))
 	}
 	// read the final data0 value
 	ops = append(ops, dmiRd(data0))
@@ -328,18 +357,27 @@ func (dbg *Debug) pbRdMemRV64(addr, n uint, pb []uint32) ([]uint64, error) {
 	if n == 1 {
 		// transfer s1 to data0/1
 		ops = append(ops, dmiWr(command, cmdRegister(regGPR(rv.RegS1), size64, cmdRead)))
-	} else {
+	} else if dbg.autoexecdata {
 		// transfer s1 to data0/1 and then postexec to get the next value in s1
 		ops = append(ops, dmiWr(command, cmdRegister(regGPR(rv.RegS1), size64, cmdRead|cmdPostExec)))
 		// turn on autoexec for data1
 		ops = append(ops, dmiWr(abstractauto, 1<<1))
-		// do n-1 data reads
+		// do n-1 data reads, each data1 read re-runs the command
 		for i := 0; i < int(n)-1; i++ {
 			ops = append(ops, dmiRd(data0))
 			ops = append(ops, dmiRd(data1))
 		}
 		// turn off autoexec
 		ops = append(ops, dmiWr(abstractauto, 0))
+	} else {
+		// no autoexec, run the command for each value
+		for i := 0; i < int(n)-1; i++ {
+			ops = append(ops, dmiWr(command, cmdRegister(regGPR(rv.RegS1), size64, cmdRead|cmdPostExec)))
+			ops = append(ops, dmiRd(data0))
+			ops = append(ops, dmiRd(data1))
+		}
+		// transfer the final s1 to data0/1
+		ops = append(ops, dmiWr(command, cmdRegister(regGPR(rv.RegS1), size64, cmdRead)))
 	}
 	// read the final data0/1 value
 	ops = append(ops, dmiRd(data0))
@@ -378,7 +416,30 @@ func (dbg *Debug) pbRdMem64(addr, n uint) ([]uint, error) {
 
 //-----------------------------------------------------------------------------
 
+// pbBurst is the maximum number of values per memory access sequence.
+const pbBurst = 256
+
+// pbRdMem reads n x width-bit values from memory using program buffer operations.
 func pbRdMem(dbg *Debug, width, addr, n uint) ([]uint, error) {
+	val := make([]uint, 0, n)
+	for n > 0 {
+		k := n
+		if k > pbBurst {
+			k = pbBurst
+		}
+		x, err := dbg.pbRdBurst(width, addr, k)
+		if err != nil {
+			return nil, err
+		}
+		val = append(val, x...)
+		addr += k * (width >> 3)
+		n -= k
+	}
+	return val, nil
+}
+
+// pbRdBurst reads a burst of n x width-bit values from memory.
+func (dbg *Debug) pbRdBurst(width, addr, n uint) ([]uint, error) {
 	switch width {
 	case 8:
 		return dbg.pbRdMem8(addr, n)
@@ -420,15 +481,21 @@ func (dbg *Debug) pbWrMemRV32(addr uint, val, pb []uint32) error {
 	ops = append(ops, dmiWr(data0, val[0]))
 	// transfer data0 to s1 and then postexec to write the value to memory.
 	ops = append(ops, dmiWr(command, cmdRegister(regGPR(rv.RegS1), size32, cmdWrite|cmdPostExec)))
-	if len(val) > 1 {
+	if len(val) > 1 && dbg.autoexecdata {
 		// turn on autoexec for data0
 		ops = append(ops, dmiWr(abstractauto, 1<<0))
-		// write the rest of the buffer
+		// write the rest of the buffer, each write re-runs the command
 		for i := 1; i < len(val); i++ {
 			ops = append(ops, dmiWr(data0, val[i]))
 		}
 		// turn off autoexec
 		ops = append(ops, dmiWr(abstractauto, 0))
+	} else {
+		// no autoexec, run the command for each value
+		for i := 1; i < len(val); i++ {
+			ops = append(ops, dmiWr(data0, val[i]))
+			ops = append(ops, dmiWr(command, cmdRegister(regGPR(rv.RegS1), size32, cmdWrite|cmdPostExec)))
+		}
 	}
 	// read the command status
 	ops = append(ops, dmiRd(abstractcs))
@@ -494,16 +561,23 @@ func (dbg *Debug) pbWrMemRV64(addr uint, val []uint64, pb []uint32) error {
 	ops = append(ops, dmiWr(data1, uint32(val[0]>>32)))
 	// transfer data0/1 to s1 and then postexec to write the value to memory.
 	ops = append(ops, dmiWr(command, cmdRegister(regGPR(rv.RegS1), size64, cmdWrite|cmdPostExec)))
-	if len(val) > 1 {
+	if len(val) > 1 && dbg.autoexecdata {
 		// turn on autoexec for data1
 		ops = append(ops, dmiWr(abstractauto, 1<<1))
-		// write the rest of the buffer
+		// write the rest of the buffer, each data1 write re-runs the command
 		for i := 1; i < len(val); i++ {
 			ops = append(ops, dmiWr(data0, uint32(val[i])))
 			ops = append(ops, dmiWr(data1, uint32(val[i]>>32)))
 		}
 		// turn off autoexec
 		ops = append(ops, dmiWr(abstractauto, 0))
+	} else {
+		// no autoexec, run the command for each value
+		for i := 1; i < len(val); i++ {
+			ops = append(ops, dmiWr(data0, uint32(val[i])))
+			ops = append(ops, dmiWr(data1, uint32(val[i]>>32)))
+			ops = append(ops, dmiWr(command, cmdRegister(regGPR(rv.RegS1), size64, cmdWrite|cmdPostExec)))
+		}
 	}
 	// read the command status
 	ops = append(ops, dmiRd(abstractcs))
@@ -529,7 +603,25 @@ func (dbg *Debug) pbWrMem64(addr uint, val []uint) error {
 
 //-----------------------------------------------------------------------------
 
+// pbWrMem writes n x width-bit values to memory using program buffer operations.
 func pbWrMem(dbg *Debug, width, addr uint, val []uint) error {
+	for len(val) > 0 {
+		k := uint(len(val))
+		if k > pbBurst {
+			k = pbBurst
+		}
+		err := dbg.pbWrBurst(width, addr, val[:k])
+		if err != nil {
+			return err
+		}
+		addr += k * (width >> 3)
+		val = val[k:]
+	}
+	return nil
+}
+
+// pbWrBurst writes a burst of width-bit values to memory.
+func (dbg *Debug) pbWrBurst(width, addr uint, val []uint) error {
 	switch width {
 	case 8:
 		return dbg.pbWrMem8(addr, val)
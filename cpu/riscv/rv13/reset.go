@@ -0,0 +1,221 @@
+//-----------------------------------------------------------------------------
+/*
+
+RISC-V Debugger 0.13
+
+Reset Functions
+
+*/
+//-----------------------------------------------------------------------------
+
+package rv13
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/deadsy/rvdbg/util"
+	"github.com/deadsy/rvdbg/util/log"
+)
+
+//-----------------------------------------------------------------------------
+
+const resetTimeout = 100 * time.Millisecond
+const srstDelay = 100 * time.Millisecond
+
+// waitStatus waits for a dmstatus flag to be set for the current hart.
+func (dbg *Debug) waitStatus(flag uint32, timeout time.Duration) (bool, error) {
+	t := time.Now().Add(timeout)
+	for {
+		set, err := dbg.checkStatus(flag)
+		if err != nil {
+			return false, err
+		}
+		if set {
+			return true, nil
+		}
+		if !t.After(time.Now()) {
+			return false, nil
+		}
+		time.Sleep(1 * time.Millisecond)
+	}
+}
+
+// waitReset waits for all harts to report that they have been reset.
+func (dbg *Debug) waitReset(halt bool) (bool, error) {
+	for i := range dbg.hart {
+		err := dbg.wrResetControl(i, 0, halt)
+		if err != nil {
+			return false, err
+		}
+		ok, err := dbg.waitStatus(allhavereset, resetTimeout)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// wrResetControl selects a hart and writes dmcontrol during a reset.
+// haltreq is write-only (it reads as 0), so a read/modify/write of dmcontrol
+// would clear it. It is held in every write until the harts have halted.
+func (dbg *Debug) wrResetControl(id int, bits uint32, halt bool) error {
+	hi := dbg.hart[id]
+	dbg.selectModule(hi.dm)
+	dbg.hartid = id
+	x := setHartSelect(dmactive|bits, hi.hartsel)
+	if halt {
+		x |= haltreq
+	}
+	return dbg.wrDmi(dmcontrol, x)
+}
+
+// wrModuleControl writes dmcontrol on a debug module during a reset.
+// The first hart of the debug module is selected.
+func (dbg *Debug) wrModuleControl(dm *debugModule, bits uint32, halt bool) error {
+	for i := range dbg.hart {
+		if dbg.hart[i].dm == dm {
+			return dbg.wrResetControl(i, bits, halt)
+		}
+	}
+	// no harts on this debug module
+	dbg.selectModule(dm)
+	return dbg.wrDmi(dmcontrol, dmactive|bits)
+}
+
+// ndmResetAll resets the system with ndmreset on all debug modules.
+func (dbg *Debug) ndmResetAll(halt bool) error {
+	for _, dm := range dbg.modules {
+		err := dbg.wrModuleControl(dm, ndmreset, halt)
+		if err != nil {
+			return err
+		}
+	}
+	for _, dm := range dbg.modules {
+		err := dbg.wrModuleControl(dm, 0, halt)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// activateAll makes each debug module active and authenticates it after a reset.
+// A debug module that was reset (or locked) ignores haltreq, so dmcontrol is
+// written again once the debug module is usable.
+func (dbg *Debug) activateAll(halt bool) error {
+	for _, dm := range dbg.modules {
+		err := dbg.wrModuleControl(dm, 0, halt)
+		if err != nil {
+			return err
+		}
+		err = authenticate(dbg, dbg.auth)
+		if err != nil {
+			return fmt.Errorf("dm%d: %v", dm.index, err)
+		}
+		err = dbg.wrModuleControl(dm, 0, halt)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reset the system, optionally halting the harts at the reset vector.
+func (dbg *Debug) reset(halt bool) error {
+
+	// setup the halt/run state for each hart after reset
+	resetHaltReq := make([]bool, len(dbg.hart))
+	for i := range dbg.hart {
+		err := dbg.wrResetControl(i, 0, halt)
+		if err != nil {
+			return err
+		}
+		resetHaltReq[i], err = dbg.checkStatus(hasresethaltreq)
+		if err != nil {
+			return err
+		}
+		if resetHaltReq[i] {
+			err = dbg.wrResetControl(i, []uint32{clrresethaltreq, setresethaltreq}[util.BoolToInt(halt)], halt)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	// reset the system with ndmreset
+	err := dbg.ndmResetAll(halt)
+	if err != nil {
+		return err
+	}
+	err = dbg.activateAll(halt)
+	if err != nil {
+		return err
+	}
+	ok, err := dbg.waitReset(halt)
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		// ndmreset didn't work, try the probe reset line
+		log.Info.Printf("ndmreset failed, using system reset")
+		err := dbg.dev.SystemReset(srstDelay)
+		if err != nil {
+			return err
+		}
+		// the debug modules may have been reset
+		err = dbg.activateAll(halt)
+		if err != nil {
+			return err
+		}
+		ok, err = dbg.waitReset(halt)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("timeout waiting for allhavereset")
+		}
+	}
+
+	// acknowledge the reset and clear the halt requests
+	for i := range dbg.hart {
+		err := dbg.wrResetControl(i, ackhavereset, halt)
+		if err != nil {
+			return err
+		}
+		if halt {
+			ok, err := dbg.waitStatus(allhalted, resetTimeout)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				log.Info.Printf("hart%d did not halt after reset", i)
+			}
+			err = dbg.wrResetControl(i, 0, false)
+			if err != nil {
+				return err
+			}
+		}
+		if resetHaltReq[i] {
+			err = dbg.wrResetControl(i, clrresethaltreq, false)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	// re-examine the harts
+	for i := range dbg.hart {
+		hi := dbg.hart[i]
+		hi.info.ClearBreakpoints()
+		err := hi.examine()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+//-----------------------------------------------------------------------------
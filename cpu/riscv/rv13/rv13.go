@@ -33,41 +33,60 @@ const irDmi = 0x11 // debug module interface access
 
 // Debug is a RISC-V 0.13 debugger. It implements the rv.Debug interface.
 type Debug struct {
-	dev             *jtag.Device
-	dmiDevice       *soc.Device // dmi device for decode/display
-	hart            []*hartInfo // implemented harts
-	hartid          int         // currently selected hart
-	ir              uint        // cache of ir value
-	irlen           int         // IR length
-	drDmiLength     int         // DR length for dmi
-	abits           uint        // address bits in dtmcs
-	idle            uint        // idle value in dtmcs
-	progbufsize     uint        // number of progbuf words implemented
-	datacount       uint        // number of data words implemented
-	autoexecprogbuf bool        // can we autoexec on progbufX access?
-	autoexecdata    bool        // can we autoexec on dataX access?
-	sbasize         uint        // width of system bus address (0 = no access)
-	hartsellen      uint        // hart select length 0..20
-	impebreak       uint        // implicit ebreak in progbuf
+	*debugModule // current debug module
+	dev          *jtag.Device
+	dmiDevice    *soc.Device    // dmi device for decode/display
+	modules      []*debugModule // debug modules on the nextdm chain
+	hart         []*hartInfo    // implemented harts
+	hartid       int            // currently selected hart
+	ir           uint           // cache of ir value
+	irlen        int            // IR length
+	drDmiLength  int            // DR length for dmi
+	abits        uint           // address bits in dtmcs
+	idle         uint           // idle value in dtmcs
+	stats        dmiStats       // dmi operation statistics
+	auth         Authenticator  // debug module authenticator
+}
+
+// dmiStats are running counts of dmi operations and errors.
+type dmiStats struct {
+	ops        uint // total dmi operations
+	busy       uint // busy results
+	fail       uint // failed/garbage results
+	retries    uint // retried operations
+	hardresets uint // dtmcs.dmihardreset recoveries
 }
 
 func (dbg *Debug) String() string {
 	s := [][]string{}
 	s = append(s, []string{"version", "0.13"})
 	s = append(s, []string{"idle cycles", fmt.Sprintf("%d", dbg.idle)})
-	s = append(s, []string{"sbasize", fmt.Sprintf("%d bits", dbg.sbasize)})
-	s = append(s, []string{"progbufsize", fmt.Sprintf("%d words", dbg.progbufsize)})
-	s = append(s, []string{"datacount", fmt.Sprintf("%d words", dbg.datacount)})
-	s = append(s, []string{"autoexecprogbuf", fmt.Sprintf("%t", dbg.autoexecprogbuf)})
-	s = append(s, []string{"autoexecdata", fmt.Sprintf("%t", dbg.autoexecdata)})
+	s = append(s, []string{"debug modules", fmt.Sprintf("%d", len(dbg.modules))})
+	for _, dm := range dbg.modules {
+		prefix := ""
+		if len(dbg.modules) > 1 {
+			prefix = fmt.Sprintf("dm%d ", dm.index)
+		}
+		s = append(s, dm.rows(prefix)...)
+	}
+	s = append(s, []string{"dmi ops", fmt.Sprintf("%d", dbg.stats.ops)})
+	s = append(s, []string{"dmi busy", fmt.Sprintf("%d", dbg.stats.busy)})
+	s = append(s, []string{"dmi fail", fmt.Sprintf("%d", dbg.stats.fail)})
+	s = append(s, []string{"dmi retries", fmt.Sprintf("%d", dbg.stats.retries)})
+	s = append(s, []string{"dmi hard resets", fmt.Sprintf("%d", dbg.stats.hardresets)})
+	for _, hi := range dbg.hart {
+		s = append(s, []string{fmt.Sprintf("hart%d memory", hi.info.ID), hi.memAccess})
+	}
 	return cli.TableString(s, []int{0, 0}, 1)
 }
 
 // New returns a RISC-V 0.13 debugger.
-func New(dev *jtag.Device) (*Debug, error) {
+// The authenticator is used for locked debug modules (nil == no authentication).
+func New(dev *jtag.Device, auth Authenticator) (*Debug, error) {
 	log.Info.Printf("0.13 debug module")
 	dbg := &Debug{
 		dev:       dev,
+		auth:      auth,
 		irlen:     dev.GetIRLength(),
 		dmiDevice: newDMI().Setup(),
 	}
@@ -99,127 +118,12 @@ func New(dev *jtag.Device) (*Debug, error) {
 		return nil, err
 	}
 
-	// make the dmi active
-	err = dbg.wrDmi(dmcontrol, 0)
-	if err != nil {
-		return nil, err
-	}
-	err = dbg.wrDmi(dmcontrol, dmactive)
-	if err != nil {
-		return nil, err
-	}
-
-	// write all-ones to hartsel
-	err = dbg.selectHart((1 << 20) - 1)
-	if err != nil {
-		return nil, err
-	}
-
-	// read back dmcontrol
-	x, err := dbg.rdDmi(dmcontrol)
-	if err != nil {
-		return nil, err
-	}
-	// check dmi is active
-	if x&dmactive == 0 {
-		return nil, errors.New("dmi is not active")
-	}
-	// work out hartsellen
-	hartsel := getHartSelect(x)
-	for hartsel&1 != 0 {
-		dbg.hartsellen++
-		hartsel >>= 1
-	}
-	log.Info.Printf("hartsellen %d", dbg.hartsellen)
-
-	// check dmstatus fields
-	x, err = dbg.rdDmi(dmstatus)
-	if err != nil {
-		return nil, err
-	}
-	// check version
-	if util.Bits(uint(x), 3, 0) != 2 {
-		return nil, errors.New("unknown dmstatus version")
-	}
-	// check authentication
-	if util.Bit(uint(x), 7) != 1 {
-		return nil, errors.New("debugger is not authenticated")
-	}
-	// implicit ebreak after progbuf
-	dbg.impebreak = util.Bit(uint(x), 22)
-
-	// work out the system bus address size
-	x, err = dbg.rdDmi(sbcs)
-	if err != nil {
-		return nil, err
-	}
-	dbg.sbasize = util.Bits(uint(x), 11, 5)
-	log.Info.Printf("sbasize %d", dbg.sbasize)
-
-	// work out how many program and data words we have
-	x, err = dbg.rdDmi(abstractcs)
-	if err != nil {
-		return nil, err
-	}
-	dbg.progbufsize = util.Bits(uint(x), 28, 24)
-	dbg.datacount = util.Bits(uint(x), 3, 0)
-
-	// check progbuf/impebreak consistency
-	if dbg.progbufsize == 1 && dbg.impebreak != 1 {
-		return nil, fmt.Errorf("progbufsize == 1 and impebreak != 1")
-	}
-
-	// work out if we can autoexec on progbuf/data access
-	err = dbg.wrDmi(abstractauto, 0xffffffff)
-	if err != nil {
-		return nil, err
-	}
-	x, err = dbg.rdDmi(abstractauto)
-	if err != nil {
-		return nil, err
-	}
-	if util.Bits(uint(x), 31, 16) == ((1 << dbg.progbufsize) - 1) {
-		dbg.autoexecprogbuf = true
-	}
-	if util.Bits(uint(x), 11, 0) == ((1 << dbg.datacount) - 1) {
-		dbg.autoexecdata = true
-	}
-	// turn off autoexec
-	err = dbg.wrDmi(abstractauto, 0)
+	// examine the debug modules on the nextdm chain
+	err = dbg.examineModules()
 	if err != nil {
 		return nil, err
 	}
 
-	log.Info.Printf("progbufsize %d impebreak %d autoexecprogbuf %t", dbg.progbufsize, dbg.impebreak, dbg.autoexecprogbuf)
-	log.Info.Printf("datacount %d autoexecdata %t", dbg.datacount, dbg.autoexecdata)
-
-	// clear any pending command errors
-	err = dbg.cmdErrorClr()
-	if err != nil {
-		return nil, err
-	}
-
-	// 1st pass: enumerate the harts
-	maxHarts := 1 << dbg.hartsellen
-	for id := 0; id < maxHarts; id++ {
-		// select the hart
-		err := dbg.selectHart(id)
-		if err != nil {
-			return nil, err
-		}
-		// get the hart status
-		x, err = dbg.rdDmi(dmstatus)
-		if err != nil {
-			return nil, err
-		}
-		if x&anynonexistent != 0 {
-			// this hart does not exist - we're done
-			break
-		}
-		// add a hart to the list
-		dbg.hart = append(dbg.hart, dbg.newHart(id))
-	}
-
 	if len(dbg.hart) == 0 {
 		return nil, errors.New("no harts found")
 	}
@@ -314,11 +218,35 @@ func (dbg *Debug) SetCurrentHart(id int) (*rv.HartInfo, error) {
 	if id < 0 || id >= len(dbg.hart) {
 		return nil, errors.New("hart id is out of range")
 	}
-	err := dbg.selectHart(id)
+	hi := dbg.hart[id]
+	dbg.selectModule(hi.dm)
+	err := dbg.selectHart(hi.hartsel)
 	dbg.hartid = id
 	return &dbg.hart[dbg.hartid].info, err
 }
 
+// GetHartState reads the state of the current hart.
+func (dbg *Debug) GetHartState() (rv.HartState, error) {
+	state := rv.Unknown
+	halted, err := dbg.isHalted()
+	if err != nil {
+		return rv.Unknown, err
+	}
+	if halted {
+		state = rv.Halted
+	} else {
+		running, err := dbg.isRunning()
+		if err != nil {
+			return rv.Unknown, err
+		}
+		if running {
+			state = rv.Running
+		}
+	}
+	dbg.hart[dbg.hartid].info.State = state
+	return state, nil
+}
+
 // HaltHart halts the current hart.
 func (dbg *Debug) HaltHart() error {
 	_, err := dbg.halt()
@@ -339,6 +267,53 @@ func (dbg *Debug) ResumeHart() error {
 	return err
 }
 
+// StepHart single steps the current hart.
+func (dbg *Debug) StepHart(ie bool) error {
+	err := dbg.step(ie)
+	halted, _ := dbg.isHalted()
+	if halted {
+		dbg.hart[dbg.hartid].info.State = rv.Halted
+	}
+	return err
+}
+
+// HaltAll halts all harts.
+// It returns true if the harts were halted together.
+func (dbg *Debug) HaltAll() (bool, error) {
+	id := dbg.hartid
+	together, err := dbg.haltAll()
+	// restore the current hart
+	_, err2 := dbg.SetCurrentHart(id)
+	if err != nil {
+		return false, err
+	}
+	return together, err2
+}
+
+// ResumeAll resumes all harts.
+// It returns true if the harts were resumed together.
+func (dbg *Debug) ResumeAll() (bool, error) {
+	id := dbg.hartid
+	together, err := dbg.resumeAll()
+	// restore the current hart
+	_, err2 := dbg.SetCurrentHart(id)
+	if err != nil {
+		return false, err
+	}
+	return together, err2
+}
+
+// Reset resets the system and re-examines the harts.
+func (dbg *Debug) Reset(halt bool) error {
+	id := dbg.hartid
+	err := dbg.reset(halt)
+	if err != nil {
+		return err
+	}
+	_, err = dbg.SetCurrentHart(id)
+	return err
+}
+
 //-----------------------------------------------------------------------------
 
 // GetPrompt returns a target prompt string.
@@ -0,0 +1,329 @@
+//-----------------------------------------------------------------------------
+/*
+
+RISC-V Debugger 0.13 System Bus Access Operations
+
+System bus accesses go directly to the bus without involving a hart.
+Memory can be read and written while the harts are running.
+Addresses are physical addresses.
+
+*/
+//-----------------------------------------------------------------------------
+
+package rv13
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/deadsy/rvdbg/util"
+)
+
+//-----------------------------------------------------------------------------
+// sbcs fields
+
+const sbbusyerror = (1 << 22)
+const sbbusy = (1 << 21)
+const sbreadonaddr = (1 << 20)
+const sbautoincrement = (1 << 16)
+const sbreadondata = (1 << 15)
+const sberrorClear = (7 << 12)
+
+// sbaccessBits returns the sbcs.sbaccess field for a width-bit access.
+func sbaccessBits(width uint) uint32 {
+	return uint32(map[uint]uint{8: 0, 16: 1, 32: 2, 64: 3, 128: 4}[width]) << 17
+}
+
+// sbAccessSupported returns true if the system bus supports width-bit accesses.
+func (dbg *Debug) sbAccessSupported(width uint) bool {
+	bit := map[uint]uint{8: 0, 16: 1, 32: 2, 64: 3, 128: 4}[width]
+	return dbg.sbasize != 0 && dbg.sbaccess&(1<<bit) != 0
+}
+
+// sbaccessString returns a string for the supported system bus access widths.
+func sbaccessString(sbaccess uint) string {
+	s := []string{}
+	for i, width := range []uint{8, 16, 32, 64, 128} {
+		if sbaccess&(1<<uint(i)) != 0 {
+			s = append(s, fmt.Sprintf("%d", width))
+		}
+	}
+	if len(s) == 0 {
+		return "none"
+	}
+	return strings.Join(s, ",") + " bits"
+}
+
+//-----------------------------------------------------------------------------
+// system bus errors
+
+// sbErr is a system bus error.
+type sbErr uint
+
+// system bus error values
+const (
+	sbErrOk          sbErr = 0
+	sbErrTimeout     sbErr = 1
+	sbErrAddress     sbErr = 2
+	sbErrAlignment   sbErr = 3
+	sbErrSize        sbErr = 4
+	sbErrReserved5   sbErr = 5
+	sbErrReserved6   sbErr = 6
+	sbErrOther       sbErr = 7
+	sbErrBusy        sbErr = 8 // sbbusyerror (not part of sberror)
+	sbErrBusyTimeout sbErr = 9 // sbbusy didn't clear (not part of sberror)
+)
+
+func (se sbErr) String() string {
+	return [10]string{
+		"ok",
+		"timeout",
+		"bad address",
+		"alignment error",
+		"unsupported access size",
+		"reserved(5)",
+		"reserved(6)",
+		"other",
+		"busy error",
+		"busy timeout",
+	}[se]
+}
+
+// sbError returns the system bus error from an sbcs value.
+func sbError(x uint32) sbErr {
+	if x&sbbusyerror != 0 {
+		return sbErrBusy
+	}
+	return sbErr(util.Bits(uint(x), 14, 12))
+}
+
+// sbCheck checks sbcs for errors, clearing them if present.
+func (dbg *Debug) sbCheck(x uint32) error {
+	se := sbError(x)
+	if se == sbErrOk {
+		return nil
+	}
+	// clear the error
+	err := dbg.wrDmi(sbcs, sbbusyerror|sberrorClear)
+	if err != nil {
+		return err
+	}
+	return fmt.Errorf("system bus error: %s", se)
+}
+
+// errSbBusy is returned when a burst access overruns the system bus.
+var errSbBusy = errors.New("system bus busy error")
+
+const sbTimeout = 10 * time.Millisecond
+
+// sbWait waits for the system bus to be not busy.
+func (dbg *Debug) sbWait() error {
+	t := time.Now().Add(sbTimeout)
+	for {
+		x, err := dbg.rdDmi(sbcs)
+		if err != nil {
+			return err
+		}
+		if x&sbbusy == 0 {
+			return dbg.sbCheck(x)
+		}
+		if !t.After(time.Now()) {
+			return fmt.Errorf("system bus error: %s", sbErrBusyTimeout)
+		}
+		time.Sleep(1 * time.Millisecond)
+	}
+}
+
+// sbAddressOps returns the dmi operations to write a system bus address.
+// The write to sbaddress0 is last since it may trigger a read.
+func (dbg *Debug) sbAddressOps(addr uint) []dmiOp {
+	ops := []dmiOp{}
+	if dbg.sbasize > 32 {
+		ops = append(ops, dmiWr(sbaddress1, uint32(addr>>32)))
+	}
+	return append(ops, dmiWr(sbaddress0, uint32(addr)))
+}
+
+// sbCheckAddress checks that an address range is accessible on the system bus.
+func (dbg *Debug) sbCheckAddress(addr, n, width uint) error {
+	if dbg.sbasize >= 64 {
+		return nil
+	}
+	end := uint64(addr) + uint64(n*(width>>3))
+	if end > (1 << dbg.sbasize) {
+		return fmt.Errorf("address 0x%x is beyond the %d-bit system bus", addr, dbg.sbasize)
+	}
+	return nil
+}
+
+// hartRdMem reads memory using the hart access method.
+func hartRdMem(dbg *Debug, width, addr, n uint) ([]uint, error) {
+	hi := dbg.hart[dbg.hartid]
+	if hi.rdMemHart == nil {
+		return nil, fmt.Errorf("%d-bit memory reads are not supported", width)
+	}
+	return hi.rdMemHart(dbg, width, addr, n)
+}
+
+// hartWrMem writes memory using the hart access method.
+func hartWrMem(dbg *Debug, width, addr uint, val []uint) error {
+	hi := dbg.hart[dbg.hartid]
+	if hi.wrMemHart == nil {
+		return fmt.Errorf("%d-bit memory writes are not supported", width)
+	}
+	return hi.wrMemHart(dbg, width, addr, val)
+}
+
+//-----------------------------------------------------------------------------
+// system bus memory reads
+
+// sbRdBurst reads n x width-bit values using auto-increment and read on data.
+func (dbg *Debug) sbRdBurst(width, addr, n uint) ([]uint, error) {
+	access := sbaccessBits(width) | sbreadonaddr | sbautoincrement
+	ops := []dmiOp{}
+	// setup sbcs, read on data for all but the last value
+	if n > 1 {
+		ops = append(ops, dmiWr(sbcs, access|sbreadondata|sbbusyerror|sberrorClear))
+	} else {
+		ops = append(ops, dmiWr(sbcs, access|sbbusyerror|sberrorClear))
+	}
+	// the address write triggers the first read
+	ops = append(ops, dbg.sbAddressOps(addr)...)
+	for i := uint(0); i < n; i++ {
+		if i == n-1 && n > 1 {
+			// don't trigger a read beyond the end of the buffer
+			ops = append(ops, dmiWr(sbcs, access))
+		}
+		if width == 64 {
+			ops = append(ops, dmiRd(sbdata1))
+		}
+		ops = append(ops, dmiRd(sbdata0))
+	}
+	// read the status
+	ops = append(ops, dmiRd(sbcs))
+	ops = append(ops, dmiEnd())
+	// run the operations
+	data, err := dbg.dmiOps(ops)
+	if err != nil {
+		return nil, err
+	}
+	// check for errors
+	status := data[len(data)-1]
+	if sbError(status) == sbErrBusy {
+		// clear the error, the caller can retry
+		err := dbg.wrDmi(sbcs, sbbusyerror|sberrorClear)
+		if err != nil {
+			return nil, err
+		}
+		return nil, errSbBusy
+	}
+	err = dbg.sbCheck(status)
+	if err != nil {
+		return nil, err
+	}
+	// return the data
+	val := make([]uint, n)
+	mask := uint((1 << width) - 1)
+	for i := range val {
+		if width == 64 {
+			val[i] = (uint(data[2*i]) << 32) | uint(data[2*i+1])
+		} else {
+			val[i] = uint(data[i]) & mask
+		}
+	}
+	return val, nil
+}
+
+// sbRdSlow reads n x width-bit values one at a time, waiting for the bus to be not busy.
+func (dbg *Debug) sbRdSlow(width, addr, n uint) ([]uint, error) {
+	err := dbg.wrDmi(sbcs, sbaccessBits(width)|sbreadonaddr|sbbusyerror|sberrorClear)
+	if err != nil {
+		return nil, err
+	}
+	val := make([]uint, n)
+	mask := uint((1 << width) - 1)
+	for i := range val {
+		// the address write triggers the read
+		ops := dbg.sbAddressOps(addr + uint(i)*(width>>3))
+		ops = append(ops, dmiEnd())
+		_, err := dbg.dmiOps(ops)
+		if err != nil {
+			return nil, err
+		}
+		err = dbg.sbWait()
+		if err != nil {
+			return nil, err
+		}
+		ops = []dmiOp{}
+		if width == 64 {
+			ops = append(ops, dmiRd(sbdata1))
+		}
+		ops = append(ops, dmiRd(sbdata0), dmiEnd())
+		data, err := dbg.dmiOps(ops)
+		if err != nil {
+			return nil, err
+		}
+		if width == 64 {
+			val[i] = (uint(data[0]) << 32) | uint(data[1])
+		} else {
+			val[i] = uint(data[0]) & mask
+		}
+	}
+	return val, nil
+}
+
+// sbRdMem reads n x width-bit values from memory using system bus operations.
+func sbRdMem(dbg *Debug, width, addr, n uint) ([]uint, error) {
+	if !dbg.sbAccessSupported(width) {
+		// the system bus doesn't support this width, use the hart
+		return hartRdMem(dbg, width, addr, n)
+	}
+	err := dbg.sbCheckAddress(addr, n, width)
+	if err != nil {
+		return nil, err
+	}
+	val, err := dbg.sbRdBurst(width, addr, n)
+	if err == errSbBusy {
+		// the bus is slower than the dmi, read one value at a time
+		return dbg.sbRdSlow(width, addr, n)
+	}
+	return val, err
+}
+
+//-----------------------------------------------------------------------------
+// system bus memory writes
+
+// sbWrMem writes n x width-bit values to memory using system bus operations.
+func sbWrMem(dbg *Debug, width, addr uint, val []uint) error {
+	if !dbg.sbAccessSupported(width) {
+		// the system bus doesn't support this width, use the hart
+		return hartWrMem(dbg, width, addr, val)
+	}
+	err := dbg.sbCheckAddress(addr, uint(len(val)), width)
+	if err != nil {
+		return err
+	}
+	ops := []dmiOp{}
+	ops = append(ops, dmiWr(sbcs, sbaccessBits(width)|sbautoincrement|sbbusyerror|sberrorClear))
+	ops = append(ops, dbg.sbAddressOps(addr)...)
+	mask := uint((1 << width) - 1)
+	for _, v := range val {
+		if width == 64 {
+			ops = append(ops, dmiWr(sbdata1, uint32(v>>32)))
+		}
+		// the write to sbdata0 triggers the bus write
+		ops = append(ops, dmiWr(sbdata0, uint32(v&mask)))
+	}
+	ops = append(ops, dmiEnd())
+	// run the operations
+	_, err = dbg.dmiOps(ops)
+	if err != nil {
+		return err
+	}
+	// wait for the last write to complete and check for errors
+	return dbg.sbWait()
+}
+
+//-----------------------------------------------------------------------------
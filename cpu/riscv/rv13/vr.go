@@ -0,0 +1,255 @@
+//-----------------------------------------------------------------------------
+/*
+
+RISC-V Debugger 0.13 Vector Register Operations
+
+Vector registers can't be accessed with abstract commands, so they are
+moved through a scratch buffer in memory using program buffer sequences:
+
+read: vsetvli s1, zero, e8, m1 ; vse8.v vN, (s0)
+write: vsetvli s1, zero, e8, m1 ; vle8.v vN, (s0)
+
+The scratch buffer is VLENB bytes below the stack pointer of the halted hart.
+The GPRs, vl, vtype, vstart, mstatus and the scratch buffer contents are
+restored after the access.
+
+*/
+//-----------------------------------------------------------------------------
+
+package rv13
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/deadsy/rvdbg/cpu/riscv/rv"
+	"github.com/deadsy/rvdbg/util"
+)
+
+//-----------------------------------------------------------------------------
+
+// vtypeE8M1 is the vtype for byte elements with 1 register per group.
+const vtypeE8M1 = 0
+
+// vrState is the hart state modified by a vector register access.
+type vrState struct {
+	s0, s1  uint64
+	vl      uint64
+	vtype   uint64
+	vstart  uint64
+	mstatus uint64
+	addr    uint   // scratch buffer address
+	scratch []uint // scratch buffer contents
+}
+
+// vrSave saves the hart state and the scratch buffer, and enables the vector unit.
+func (dbg *Debug) vrSave(addr uint) (*vrState, error) {
+	hi := dbg.hart[dbg.hartid]
+	st := &vrState{addr: addr}
+	var err error
+	st.s0, err = dbg.RdGPR(rv.RegS0, 0)
+	if err != nil {
+		return nil, err
+	}
+	st.s1, err = dbg.RdGPR(rv.RegS1, 0)
+	if err != nil {
+		return nil, err
+	}
+	st.scratch, err = dbg.vrRdMem(addr, hi.info.VLENB)
+	if err != nil {
+		return nil, err
+	}
+	// the vector csrs are illegal with mstatus.vs == off
+	st.mstatus, err = rv.EnableVector(dbg)
+	if err != nil {
+		return nil, err
+	}
+	err = dbg.vrSaveCSR(st)
+	if err != nil {
+		// put back mstatus.vs
+		dbg.WrCSR(rv.MSTATUS, 0, st.mstatus)
+		return nil, err
+	}
+	return st, nil
+}
+
+// vrSaveCSR saves the vector csrs and clears vstart.
+func (dbg *Debug) vrSaveCSR(st *vrState) error {
+	var err error
+	st.vl, err = dbg.RdCSR(rv.VL, 0)
+	if err != nil {
+		return err
+	}
+	st.vtype, err = dbg.RdCSR(rv.VTYPE, 0)
+	if err != nil {
+		return err
+	}
+	st.vstart, err = dbg.RdCSR(rv.VSTART, 0)
+	if err != nil {
+		return err
+	}
+	// vle8/vse8 start at element vstart
+	return dbg.WrCSR(rv.VSTART, 0, 0)
+}
+
+// vrRestore restores the hart state (dirty: the vector state was modified).
+func (dbg *Debug) vrRestore(st *vrState, dirty bool) error {
+	hi := dbg.hart[dbg.hartid]
+	// vsetvl zero, s0, s1 restores vl and vtype
+	err := dbg.WrGPR(rv.RegS1, 0, st.vtype)
+	if err != nil {
+		return err
+	}
+	pb := dbg.newProgramBuffer(2)
+	pb[0] = rv.InsVSETVL(rv.RegZero, rv.RegS0, rv.RegS1)
+	err = dbg.pbWrite(hi.info.MXLEN, st.vl, pb)
+	if err != nil {
+		return err
+	}
+	err = dbg.WrCSR(rv.VSTART, 0, st.vstart)
+	if err != nil {
+		return err
+	}
+	mstatus := st.mstatus
+	if dirty && mstatus&rv.MstatusVS != 0 {
+		mstatus |= rv.MstatusVSDirty
+	}
+	err = dbg.WrCSR(rv.MSTATUS, 0, mstatus)
+	if err != nil {
+		return err
+	}
+	err = dbg.vrWrMem(st.addr, st.scratch)
+	if err != nil {
+		return err
+	}
+	err = dbg.WrGPR(rv.RegS0, 0, st.s0)
+	if err != nil {
+		return err
+	}
+	return dbg.WrGPR(rv.RegS1, 0, st.s1)
+}
+
+// vrScratch returns the address of the scratch buffer.
+func (dbg *Debug) vrScratch() (uint, error) {
+	hi := dbg.hart[dbg.hartid]
+	sp, err := dbg.RdGPR(rv.RegSp, 0)
+	if err != nil {
+		return 0, err
+	}
+	if uint(sp) < hi.info.VLENB+16 {
+		return 0, fmt.Errorf("sp 0x%x is too low for the vector scratch buffer", sp)
+	}
+	return (uint(sp) - hi.info.VLENB) &^ 15, nil
+}
+
+// vrAccess runs a vector load/store on the scratch buffer.
+func (dbg *Debug) vrAccess(ins uint32, addr uint) error {
+	hi := dbg.hart[dbg.hartid]
+	// We need 2 instructions + ebreak.
+	if dbg.progbufsize < 2 || (dbg.progbufsize == 2 && dbg.impebreak == 0) {
+		return errors.New("program buffer is too small for vector register access")
+	}
+	pb := dbg.newProgramBuffer(3)
+	pb[0] = rv.InsVSETVLI(rv.RegS1, rv.RegZero, vtypeE8M1)
+	pb[1] = ins
+	return dbg.pbWrite(hi.info.MXLEN, uint64(addr), pb)
+}
+
+// vrCheck checks the hart state for a vector register access.
+func (dbg *Debug) vrCheck(reg uint) error {
+	hi := dbg.hart[dbg.hartid]
+	if hi.info.VLENB == 0 {
+		return fmt.Errorf("hart%d has no vector registers", hi.info.ID)
+	}
+	if reg >= 32 {
+		return fmt.Errorf("vr%d is invalid", reg)
+	}
+	return nil
+}
+
+//-----------------------------------------------------------------------------
+
+// vrRdMem reads the scratch buffer using the hart if possible.
+func (dbg *Debug) vrRdMem(addr, n uint) ([]uint, error) {
+	hi := dbg.hart[dbg.hartid]
+	if hi.rdMemHart != nil {
+		return hi.rdMemHart(dbg, 8, addr, n)
+	}
+	return hi.rdMem(dbg, 8, addr, n)
+}
+
+// vrWrMem writes the scratch buffer using the hart if possible.
+func (dbg *Debug) vrWrMem(addr uint, val []uint) error {
+	hi := dbg.hart[dbg.hartid]
+	if hi.wrMemHart != nil {
+		return hi.wrMemHart(dbg, 8, addr, val)
+	}
+	return hi.wrMem(dbg, 8, addr, val)
+}
+
+// RdVR reads a vector register.
+func (dbg *Debug) RdVR(reg uint) ([]uint8, error) {
+	err := dbg.vrCheck(reg)
+	if err != nil {
+		return nil, err
+	}
+	hi := dbg.hart[dbg.hartid]
+	addr, err := dbg.vrScratch()
+	if err != nil {
+		return nil, err
+	}
+	st, err := dbg.vrSave(addr)
+	if err != nil {
+		return nil, err
+	}
+	var buf []uint
+	err = dbg.vrAccess(rv.InsVSE8(reg, rv.RegS0), addr)
+	if err == nil {
+		buf, err = dbg.vrRdMem(addr, hi.info.VLENB)
+	}
+	// always restore the hart state
+	rerr := dbg.vrRestore(st, false)
+	if err != nil {
+		return nil, err
+	}
+	if rerr != nil {
+		return nil, rerr
+	}
+	return util.CastUintto8(buf), nil
+}
+
+// WrVR writes a vector register.
+func (dbg *Debug) WrVR(reg uint, val []uint8) error {
+	err := dbg.vrCheck(reg)
+	if err != nil {
+		return err
+	}
+	hi := dbg.hart[dbg.hartid]
+	if uint(len(val)) != hi.info.VLENB {
+		return fmt.Errorf("vr%d write needs %d bytes", reg, hi.info.VLENB)
+	}
+	addr, err := dbg.vrScratch()
+	if err != nil {
+		return err
+	}
+	st, err := dbg.vrSave(addr)
+	if err != nil {
+		return err
+	}
+	buf := make([]uint, len(val))
+	for i := range val {
+		buf[i] = uint(val[i])
+	}
+	err = dbg.vrWrMem(addr, buf)
+	if err == nil {
+		err = dbg.vrAccess(rv.InsVLE8(reg, rv.RegS0), addr)
+	}
+	// always restore the hart state
+	rerr := dbg.vrRestore(st, err == nil)
+	if err != nil {
+		return err
+	}
+	return rerr
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,502 @@
+//-----------------------------------------------------------------------------
+/*
+
+RISC-V Semihosting
+
+A semihosting call is an ebreak wrapped in a magic instruction sequence:
+
+slli x0, x0, 0x1f
+ebreak
+srai x0, x0, 7
+
+a0 holds the operation number and a1 holds the parameter (normally a pointer
+to an XLEN word parameter block). The result is returned in a0.
+The operation numbers and semantics follow the ARM semihosting specification.
+
+The ebreak only enters debug mode if dcsr.ebreakm/s/u are set, so enabling
+semihosting sets them on each hart. They are cleared by a hart reset, so the
+reset command sets them again.
+
+The CLI owns the terminal, so console input (stdin) is provided with the
+"semihost input" command. A console read with no input leaves the hart halted
+until input is provided.
+
+*/
+//-----------------------------------------------------------------------------
+
+package riscv
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	cli "github.com/deadsy/go-cli"
+	"github.com/deadsy/rvdbg/cpu/riscv/rv"
+	"github.com/deadsy/rvdbg/util"
+)
+
+//-----------------------------------------------------------------------------
+
+// semihosting instruction sequence
+const (
+	insSemiEntry = 0x01f01013 // slli x0, x0, 0x1f
+	insSemiExit  = 0x40705013 // srai x0, x0, 7
+	insEBREAK    = 0x00100073 // ebreak
+)
+
+// semihosting operations
+const (
+	sysOpen   = 0x01
+	sysClose  = 0x02
+	sysWriteC = 0x03
+	sysWrite0 = 0x04
+	sysWrite  = 0x05
+	sysRead   = 0x06
+	sysClock  = 0x10
+	sysExit   = 0x18
+)
+
+// adpStoppedApplicationExit is the SYS_EXIT reason for a normal exit.
+const adpStoppedApplicationExit = 0x20026
+
+// console file handles
+const (
+	handleStdin  = 0
+	handleStdout = 1
+	handleStderr = 2
+)
+
+// maxString is the maximum length of a target string.
+const maxString = 4096
+
+// semihostInterval is the poll interval after a semihosting call.
+const semihostInterval = 5 * time.Millisecond
+
+//-----------------------------------------------------------------------------
+
+// semihost is the semihosting state.
+type semihost struct {
+	enabled bool
+	start   time.Time         // start time for SYS_CLOCK
+	files   map[uint]*os.File // open host files
+	next    uint              // next file handle
+	stdin   []byte            // console input
+	waiting map[int]bool      // harts waiting for console input
+}
+
+var semihosting = &semihost{}
+
+// openMode maps the SYS_OPEN mode to os.OpenFile flags.
+var openMode = [12]int{
+	os.O_RDONLY,                             // r
+	os.O_RDONLY,                             // rb
+	os.O_RDWR,                               // r+
+	os.O_RDWR,                               // r+b
+	os.O_WRONLY | os.O_CREATE | os.O_TRUNC,  // w
+	os.O_WRONLY | os.O_CREATE | os.O_TRUNC,  // wb
+	os.O_RDWR | os.O_CREATE | os.O_TRUNC,    // w+
+	os.O_RDWR | os.O_CREATE | os.O_TRUNC,    // w+b
+	os.O_WRONLY | os.O_CREATE | os.O_APPEND, // a
+	os.O_WRONLY | os.O_CREATE | os.O_APPEND, // ab
+	os.O_RDWR | os.O_CREATE | os.O_APPEND,   // a+
+	os.O_RDWR | os.O_CREATE | os.O_APPEND,   // a+b
+}
+
+// enable turns semihosting on/off.
+func (sh *semihost) enable(on bool) {
+	if on && !sh.enabled {
+		sh.start = time.Now()
+		sh.files = make(map[uint]*os.File)
+		sh.next = handleStderr + 1
+		sh.stdin = nil
+		sh.waiting = make(map[int]bool)
+	}
+	if !on && sh.enabled {
+		for _, f := range sh.files {
+			f.Close()
+		}
+		sh.files = nil
+	}
+	sh.enabled = on
+}
+
+//-----------------------------------------------------------------------------
+// target memory access
+
+// rdParams reads n XLEN words from the parameter block.
+func rdParams(dbg rv.Debug, addr uint, n uint) ([]uint, error) {
+	return dbg.RdMem(dbg.GetCurrentHart().MXLEN, addr, n)
+}
+
+// rdBytes reads a byte buffer from target memory.
+func rdBytes(dbg rv.Debug, addr, n uint) ([]byte, error) {
+	if n == 0 {
+		return nil, nil
+	}
+	x, err := dbg.RdMem(8, addr, n)
+	if err != nil {
+		return nil, err
+	}
+	return util.CastUintto8(x), nil
+}
+
+// rdString reads a NUL terminated string from target memory.
+func rdString(dbg rv.Debug, addr uint) (string, error) {
+	const chunk = 64
+	s := []byte{}
+	for len(s) < maxString {
+		buf, err := rdBytes(dbg, addr, chunk)
+		if err != nil {
+			return "", err
+		}
+		for _, c := range buf {
+			if c == 0 {
+				return string(s), nil
+			}
+			s = append(s, c)
+		}
+		addr += chunk
+	}
+	return "", fmt.Errorf("string at 0x%x is longer than %d bytes", addr, maxString)
+}
+
+//-----------------------------------------------------------------------------
+// semihosting operations
+
+// open opens a host file or the console.
+func (sh *semihost) open(dbg rv.Debug, arg uint) (int, error) {
+	p, err := rdParams(dbg, arg, 3)
+	if err != nil {
+		return 0, err
+	}
+	buf, err := rdBytes(dbg, p[0], p[2])
+	if err != nil {
+		return 0, err
+	}
+	name, mode := string(buf), p[1]
+	if mode >= uint(len(openMode)) {
+		return -1, nil
+	}
+	if name == ":tt" {
+		return []int{handleStdin, handleStdout, handleStderr}[mode>>2], nil
+	}
+	f, err := os.OpenFile(name, openMode[mode], 0644)
+	if err != nil {
+		return -1, nil
+	}
+	h := sh.next
+	sh.next++
+	sh.files[h] = f
+	return int(h), nil
+}
+
+// close closes a host file.
+func (sh *semihost) close(dbg rv.Debug, arg uint) (int, error) {
+	p, err := rdParams(dbg, arg, 1)
+	if err != nil {
+		return 0, err
+	}
+	if p[0] <= handleStderr {
+		return 0, nil
+	}
+	f, ok := sh.files[p[0]]
+	if !ok {
+		return -1, nil
+	}
+	delete(sh.files, p[0])
+	if f.Close() != nil {
+		return -1, nil
+	}
+	return 0, nil
+}
+
+// write writes a buffer to a host file or the console.
+// It returns the number of bytes not written.
+func (sh *semihost) write(dbg rv.Debug, user cli.USER, arg uint) (int, error) {
+	p, err := rdParams(dbg, arg, 3)
+	if err != nil {
+		return 0, err
+	}
+	buf, err := rdBytes(dbg, p[1], p[2])
+	if err != nil {
+		return 0, err
+	}
+	switch p[0] {
+	case handleStdout, handleStderr:
+		user.Put(string(buf))
+		return 0, nil
+	}
+	f, ok := sh.files[p[0]]
+	if !ok {
+		return len(buf), nil
+	}
+	n, _ := f.Write(buf)
+	return len(buf) - n, nil
+}
+
+// read reads a buffer from a host file or the console.
+// It returns the number of bytes not read.
+// wait is true if a console read has no input.
+func (sh *semihost) read(dbg rv.Debug, arg uint) (result int, wait bool, err error) {
+	p, err := rdParams(dbg, arg, 3)
+	if err != nil {
+		return 0, false, err
+	}
+	var buf []byte
+	var n int
+	if p[0] == handleStdin {
+		if len(sh.stdin) == 0 {
+			return 0, true, nil
+		}
+		n = len(sh.stdin)
+		if n > int(p[2]) {
+			n = int(p[2])
+		}
+		buf = sh.stdin[:n]
+		sh.stdin = sh.stdin[n:]
+	} else {
+		f, ok := sh.files[p[0]]
+		if !ok {
+			return -1, false, nil
+		}
+		buf = make([]byte, p[2])
+		n, err = io.ReadFull(f, buf)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return -1, false, nil
+		}
+	}
+	if n != 0 {
+		val := make([]uint, n)
+		for i := range val {
+			val[i] = uint(buf[i])
+		}
+		err = dbg.WrMem(8, p[1], val)
+		if err != nil {
+			return 0, false, err
+		}
+	}
+	return int(p[2]) - n, false, nil
+}
+
+// input adds console input and services the harts waiting for it.
+func (sh *semihost) input(dbg rv.Debug, user cli.USER, s string) error {
+	sh.stdin = append(sh.stdin, s...)
+	id := dbg.GetCurrentHart().ID
+	defer dbg.SetCurrentHart(id)
+	for i := range sh.waiting {
+		_, err := dbg.SetCurrentHart(i)
+		if err != nil {
+			return err
+		}
+		_, msg, err := sh.service(dbg, user)
+		if err != nil {
+			return err
+		}
+		if msg != "" {
+			user.Put(msg + "\n")
+		}
+	}
+	return nil
+}
+
+// sysExitString returns the exit report.
+func sysExitString(dbg rv.Debug, arg uint) (string, error) {
+	hi := dbg.GetCurrentHart()
+	reason, code := arg, uint(0)
+	if hi.MXLEN == 64 {
+		// 64-bit targets pass a parameter block
+		p, err := rdParams(dbg, arg, 2)
+		if err != nil {
+			return "", err
+		}
+		reason, code = p[0], p[1]
+	}
+	if reason == adpStoppedApplicationExit {
+		return fmt.Sprintf("hart%d semihosting exit: %d", hi.ID, code), nil
+	}
+	return fmt.Sprintf("hart%d semihosting exit: reason 0x%x", hi.ID, reason), nil
+}
+
+//-----------------------------------------------------------------------------
+
+// isSemihostCall returns true if the hart has halted on a semihosting call.
+func isSemihostCall(dbg rv.Debug, pc uint) (bool, error) {
+	cause, err := rv.GetHaltCause(dbg)
+	if err != nil {
+		return false, err
+	}
+	if cause != rv.CauseEbreak {
+		return false, nil
+	}
+	// read as 16-bit values, the sequence may not be 32-bit aligned
+	x, err := dbg.RdMem(16, pc-4, 6)
+	if err != nil {
+		// not readable, not a semihosting call
+		return false, nil
+	}
+	ins := []uint{x[0] | x[1]<<16, x[2] | x[3]<<16, x[4] | x[5]<<16}
+	return ins[0] == insSemiEntry && ins[1] == insEBREAK && ins[2] == insSemiExit, nil
+}
+
+// call services a semihosting call for the current (halted) hart.
+// It returns true if the call was serviced and the pc has been moved past it.
+// Otherwise a non-empty string reports why the hart remains halted.
+func (sh *semihost) call(dbg rv.Debug, user cli.USER) (bool, string, error) {
+	hi := dbg.GetCurrentHart()
+	x, err := dbg.RdCSR(rv.DPC, 0)
+	if err != nil {
+		return false, "", err
+	}
+	pc := uint(x)
+	ok, err := isSemihostCall(dbg, pc)
+	if err != nil || !ok {
+		return false, "", err
+	}
+	x, err = dbg.RdGPR(rv.RegA0, 0)
+	if err != nil {
+		return false, "", err
+	}
+	op := uint(x)
+	x, err = dbg.RdGPR(rv.RegA1, 0)
+	if err != nil {
+		return false, "", err
+	}
+	arg := uint(x)
+
+	var result int
+	switch op {
+	case sysOpen:
+		result, err = sh.open(dbg, arg)
+	case sysClose:
+		result, err = sh.close(dbg, arg)
+	case sysWriteC:
+		var buf []byte
+		buf, err = rdBytes(dbg, arg, 1)
+		user.Put(string(buf))
+	case sysWrite0:
+		var s string
+		s, err = rdString(dbg, arg)
+		user.Put(s)
+	case sysWrite:
+		result, err = sh.write(dbg, user, arg)
+	case sysRead:
+		var wait bool
+		result, wait, err = sh.read(dbg, arg)
+		if err == nil && wait {
+			sh.waiting[hi.ID] = true
+			return false, fmt.Sprintf("hart%d is waiting for console input", hi.ID), nil
+		}
+		delete(sh.waiting, hi.ID)
+	case sysClock:
+		result = int(time.Since(sh.start) / (10 * time.Millisecond))
+	case sysExit:
+		s, err := sysExitString(dbg, arg)
+		return false, s, err
+	default:
+		return false, fmt.Sprintf("hart%d unsupported semihosting operation 0x%x", hi.ID, op), nil
+	}
+	if err != nil {
+		return false, "", err
+	}
+
+	// return the result and skip the ebreak
+	err = dbg.WrGPR(rv.RegA0, 0, uint64(result)&uint64(util.Mask(hi.MXLEN-1, 0)))
+	if err != nil {
+		return false, "", err
+	}
+	err = dbg.WrCSR(rv.DPC, 0, uint64(pc+4))
+	if err != nil {
+		return false, "", err
+	}
+	return true, "", nil
+}
+
+// service services a semihosting call for the current (halted) hart.
+// It returns true if the call was serviced and the hart resumed.
+// Otherwise a non-empty string reports why the hart remains halted.
+func (sh *semihost) service(dbg rv.Debug, user cli.USER) (bool, string, error) {
+	ok, msg, err := sh.call(dbg, user)
+	if err != nil || !ok {
+		return false, msg, err
+	}
+	return true, "", dbg.ResumeHart()
+}
+
+//-----------------------------------------------------------------------------
+
+// setSemihosting enables/disables semihosting ebreaks for all harts.
+func setSemihosting(dbg rv.Debug, on bool) error {
+	id := dbg.GetCurrentHart().ID
+	defer dbg.SetCurrentHart(id)
+	for i := 0; i < dbg.GetHartCount(); i++ {
+		_, err := dbg.SetCurrentHart(i)
+		if err != nil {
+			return err
+		}
+		err = haltedDo(dbg, func() error {
+			return rv.SetSemihosting(dbg, on)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//-----------------------------------------------------------------------------
+
+// SemihostHelp is help information for the "semihost" command.
+var SemihostHelp = []cli.Help{
+	{"<cr>", "display the semihosting state"},
+	{"[on|off]", "enable/disable semihosting"},
+	{"input <text>", "console input (a newline is added)"},
+}
+
+// CmdSemihost enables/disables semihosting.
+var CmdSemihost = cli.Leaf{
+	Descr: "semihosting control",
+	F: func(c *cli.CLI, args []string) {
+		if len(args) != 0 && args[0] == "input" {
+			if !semihosting.enabled {
+				c.User.Put("semihosting is off\n")
+				return
+			}
+			dbg := c.User.(target).GetRiscvDebug()
+			err := semihosting.input(dbg, c.User, strings.Join(args[1:], " ")+"\n")
+			if err != nil {
+				c.User.Put(fmt.Sprintf("%s\n", err))
+			}
+			return
+		}
+		err := cli.CheckArgc(args, []int{0, 1})
+		if err != nil {
+			c.User.Put(fmt.Sprintf("%s\n", err))
+			return
+		}
+		if len(args) == 1 {
+			dbg := c.User.(target).GetRiscvDebug()
+			var on bool
+			switch args[0] {
+			case "on":
+				on = true
+			case "off":
+				on = false
+			default:
+				c.User.Put("argument must be \"on\" or \"off\"\n")
+				return
+			}
+			err := setSemihosting(dbg, on)
+			if err != nil {
+				c.User.Put(fmt.Sprintf("unable to set dcsr ebreak bits: %v\n", err))
+				return
+			}
+			semihosting.enable(on)
+		}
+		c.User.Put(fmt.Sprintf("semihosting is %s\n", []string{"off", "on"}[util.BoolToInt(semihosting.enabled)]))
+	},
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,94 @@
+//-----------------------------------------------------------------------------
+/*
+
+Program Symbols
+
+The symbol table of the running program is loaded from its ELF file.
+Symbols can then be used as address arguments and code addresses are
+displayed as func+offset.
+
+*/
+//-----------------------------------------------------------------------------
+
+package riscv
+
+import (
+	"fmt"
+
+	cli "github.com/deadsy/go-cli"
+	"github.com/deadsy/rvdbg/mem"
+)
+
+//-----------------------------------------------------------------------------
+
+// Symbols is the program symbol table of a target.
+type Symbols struct {
+	table *mem.SymbolTable // nil == not loaded
+}
+
+// NewSymbols returns an empty program symbol table.
+func NewSymbols() *Symbols {
+	return &Symbols{}
+}
+
+// Region returns the memory region for a program symbol (or nil).
+func (s *Symbols) Region(name string) *mem.Region {
+	x := s.Lookup(name)
+	if x == nil {
+		return nil
+	}
+	return x.Region()
+}
+
+// Lookup returns a program symbol by name (or nil).
+func (s *Symbols) Lookup(name string) *mem.Symbol {
+	if s == nil || s.table == nil {
+		return nil
+	}
+	return s.table.Lookup(name)
+}
+
+// AddrString returns the func+offset string for an address.
+func (s *Symbols) AddrString(addr uint) string {
+	if s == nil || s.table == nil {
+		return ""
+	}
+	return s.table.AddrString(addr)
+}
+
+//-----------------------------------------------------------------------------
+
+// SymbolsHelp is help information for the "symbols" command.
+var SymbolsHelp = []cli.Help{
+	{"<cr>", "display the symbol table state"},
+	{"<filename>", "read the symbol table from an elf file"},
+	{"  filename", "name of file (string)"},
+}
+
+// CmdSymbols reads the program symbol table.
+var CmdSymbols = cli.Leaf{
+	Descr: "read the program symbol table",
+	F: func(c *cli.CLI, args []string) {
+		err := cli.CheckArgc(args, []int{0, 1})
+		if err != nil {
+			c.User.Put(fmt.Sprintf("%s\n", err))
+			return
+		}
+		syms := c.User.(target).GetSymbols()
+		if len(args) == 1 {
+			t, err := mem.ReadSymbols(args[0])
+			if err != nil {
+				c.User.Put(fmt.Sprintf("unable to read symbols: %v\n", err))
+				return
+			}
+			syms.table = t
+		}
+		if syms.table == nil {
+			c.User.Put("no symbol table\n")
+			return
+		}
+		c.User.Put(fmt.Sprintf("%d symbols from %s\n", syms.table.Len(), syms.table.Filename))
+	},
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,212 @@
+//-----------------------------------------------------------------------------
+/*
+
+RISC-V Vector Registers
+
+Display and write the vector register set.
+Each register is shown as element lanes of the current SEW (vtype.vsew),
+element 0 first. If vtype.vill is set the lanes are shown as bytes.
+
+*/
+//-----------------------------------------------------------------------------
+
+package riscv
+
+import (
+	"fmt"
+	"strings"
+
+	cli "github.com/deadsy/go-cli"
+	"github.com/deadsy/rvdbg/cpu/riscv/rv"
+	"github.com/deadsy/rvdbg/util"
+)
+
+//-----------------------------------------------------------------------------
+
+// vregNumber returns the register number for a vector register name (v3).
+func vregNumber(name string) (uint, error) {
+	for i := 0; i < 32; i++ {
+		if name == fmt.Sprintf("v%d", i) {
+			return uint(i), nil
+		}
+	}
+	return 0, fmt.Errorf("no register \"%s\"", name)
+}
+
+// vtypeSEW returns the selected element width for a vtype value.
+func vtypeSEW(vtype, xlen uint) uint {
+	if util.Bit(vtype, xlen-1) != 0 {
+		// vill
+		return 8
+	}
+	vsew := util.Bits(vtype, 5, 3)
+	if vsew > 3 {
+		// reserved
+		return 8
+	}
+	return 8 << vsew
+}
+
+// vtypeString returns a string for the vtype value.
+func vtypeString(vtype, xlen uint) string {
+	if util.Bit(vtype, xlen-1) != 0 {
+		return "vill"
+	}
+	s := []string{}
+	if x, ok := rv.VectorSEW[util.Bits(vtype, 5, 3)]; ok {
+		s = append(s, x)
+	} else {
+		s = append(s, "e?")
+	}
+	if x, ok := rv.VectorLMUL[util.Bits(vtype, 2, 0)]; ok {
+		s = append(s, x)
+	} else {
+		s = append(s, "m?")
+	}
+	s = append(s, []string{"tu", "ta"}[util.Bit(vtype, 6)])
+	s = append(s, []string{"mu", "ma"}[util.Bit(vtype, 7)])
+	return strings.Join(s, ",")
+}
+
+// vregLanes returns the sew-bit element lanes for a vector register value.
+func vregLanes(buf []uint8, sew uint) []uint {
+	lanes := make([]uint, len(buf)/int(sew>>3))
+	util.ConvertFromUint8(sew, buf, lanes)
+	return lanes
+}
+
+// vregString returns the display string for a vector register.
+func vregString(reg uint, buf []uint8, sew uint) string {
+	lanes := vregLanes(buf, sew)
+	fmtx := util.UintFormat(sew)
+	s := make([]string, len(lanes))
+	for i := range lanes {
+		s[i] = fmt.Sprintf(fmtx, lanes[i])
+	}
+	return fmt.Sprintf("%-4s %s", fmt.Sprintf("v%d", reg), strings.Join(s, " "))
+}
+
+//-----------------------------------------------------------------------------
+
+// vregWrite writes element lanes (starting at element 0) to a vector register.
+func vregWrite(dbg rv.Debug, reg, sew uint, args []string) error {
+	buf, err := dbg.RdVR(reg)
+	if err != nil {
+		return err
+	}
+	lanes := vregLanes(buf, sew)
+	if len(args) > len(lanes) {
+		return fmt.Errorf("v%d has %d e%d lanes", reg, len(lanes), sew)
+	}
+	for i := range args {
+		lanes[i], err = cli.UintArg(args[i], [2]uint{0, util.Mask(sew-1, 0)}, 16)
+		if err != nil {
+			return err
+		}
+	}
+	return dbg.WrVR(reg, util.ConvertToUint8(sew, lanes))
+}
+
+// vregCSR returns the vtype, vl and vstart values.
+// The vector unit is enabled for the reads and then restored.
+func vregCSR(dbg rv.Debug) ([]uint, error) {
+	mstatus, err := rv.EnableVector(dbg)
+	if err != nil {
+		return nil, fmt.Errorf("unable to enable the vector unit: %v", err)
+	}
+	csr := []uint{rv.VTYPE, rv.VL, rv.VSTART}
+	val := make([]uint, len(csr))
+	for i := range csr {
+		var x uint64
+		x, err = dbg.RdCSR(csr[i], 0)
+		if err != nil {
+			err = fmt.Errorf("unable to read csr 0x%x: %v", csr[i], err)
+			break
+		}
+		val[i] = uint(x)
+	}
+	// always restore mstatus
+	rerr := dbg.WrCSR(rv.MSTATUS, 0, mstatus)
+	if err != nil {
+		return nil, err
+	}
+	if rerr != nil {
+		return nil, fmt.Errorf("unable to restore mstatus: %v", rerr)
+	}
+	return val, nil
+}
+
+//-----------------------------------------------------------------------------
+
+// VregHelp is help information for the "vreg" command.
+var VregHelp = []cli.Help{
+	{"<cr>", "display all registers"},
+	{"<reg>", "display a register"},
+	{"<reg> <lane0> [lane1 ...]", "write register lanes (hex)"},
+	{"  reg", "register name (v0..v31)"},
+	{"  lane", "element value for the current SEW"},
+}
+
+// CmdVreg displays and writes the vector registers.
+var CmdVreg = cli.Leaf{
+	Descr: "display/write vector registers",
+	F: func(c *cli.CLI, args []string) {
+		dbg := c.User.(target).GetRiscvDebug()
+		hi := dbg.GetCurrentHart()
+		if hi.VLENB == 0 {
+			c.User.Put(fmt.Sprintf("hart%d has no vector registers\n", hi.ID))
+			return
+		}
+		err := dbg.HaltHart()
+		if err != nil {
+			c.User.Put(fmt.Sprintf("unable to halt hart%d: %v\n", hi.ID, err))
+			return
+		}
+		// read the vector csrs
+		val, err := vregCSR(dbg)
+		if err != nil {
+			c.User.Put(fmt.Sprintf("%s\n", err))
+			return
+		}
+		vtype, vl, vstart := val[0], val[1], val[2]
+		sew := vtypeSEW(vtype, hi.MXLEN)
+		if len(args) != 0 {
+			name := strings.ToLower(args[0])
+			reg, err := vregNumber(name)
+			if err != nil {
+				c.User.Put(fmt.Sprintf("%s\n", err))
+				return
+			}
+			if len(args) > 1 {
+				err = vregWrite(dbg, reg, sew, args[1:])
+				if err != nil {
+					c.User.Put(fmt.Sprintf("unable to write %s: %v\n", name, err))
+				}
+				return
+			}
+			buf, err := dbg.RdVR(reg)
+			if err != nil {
+				c.User.Put(fmt.Sprintf("unable to read %s: %v\n", name, err))
+				return
+			}
+			c.User.Put(fmt.Sprintf("%s\n", vregString(reg, buf, sew)))
+			return
+		}
+		// display the vector csrs
+		c.User.Put(fmt.Sprintf("%-6s "+util.UintFormat(hi.MXLEN)+" %s\n", "vtype", vtype, vtypeString(vtype, hi.MXLEN)))
+		c.User.Put(fmt.Sprintf("%-6s %d\n", "vl", vl))
+		c.User.Put(fmt.Sprintf("%-6s %d\n", "vstart", vstart))
+		c.User.Put(fmt.Sprintf("%-6s %d bits\n", "vlen", hi.VLENB*8))
+		// display the registers
+		for i := uint(0); i < 32; i++ {
+			buf, err := dbg.RdVR(i)
+			if err != nil {
+				c.User.Put(fmt.Sprintf("unable to read v%d: %v\n", i, err))
+				return
+			}
+			c.User.Put(fmt.Sprintf("%s\n", vregString(i, buf, sew)))
+		}
+	},
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,57 @@
+//-----------------------------------------------------------------------------
+/*
+
+RISC-V Virtual to Physical Address Translation
+
+*/
+//-----------------------------------------------------------------------------
+
+package riscv
+
+import (
+	"fmt"
+
+	cli "github.com/deadsy/go-cli"
+	"github.com/deadsy/rvdbg/cpu/riscv/rv"
+	"github.com/deadsy/rvdbg/util"
+)
+
+//-----------------------------------------------------------------------------
+
+// VtopHelp is help information for the "vtop" command.
+var VtopHelp = []cli.Help{
+	{"<addr>", "translate a virtual address"},
+	{"  addr", "virtual address (hex)"},
+}
+
+// CmdVtop walks the page tables to translate a virtual address.
+var CmdVtop = cli.Leaf{
+	Descr: "virtual to physical address translation",
+	F: func(c *cli.CLI, args []string) {
+		err := cli.CheckArgc(args, []int{1})
+		if err != nil {
+			c.User.Put(fmt.Sprintf("%s\n", err))
+			return
+		}
+		dbg := c.User.(target).GetRiscvDebug()
+		hi := dbg.GetCurrentHart()
+		va, err := cli.UintArg(args[0], [2]uint{0, util.Mask(hi.MXLEN-1, 0)}, 16)
+		if err != nil {
+			c.User.Put(fmt.Sprintf("%s\n", err))
+			return
+		}
+		err = dbg.HaltHart()
+		if err != nil {
+			c.User.Put(fmt.Sprintf("unable to halt hart%d: %v\n", hi.ID, err))
+			return
+		}
+		t, err := rv.Translate(dbg, va)
+		if err != nil {
+			c.User.Put(fmt.Sprintf("%s\n", err))
+			return
+		}
+		c.User.Put(fmt.Sprintf("%s\n", t))
+	},
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,173 @@
+//-----------------------------------------------------------------------------
+/*
+
+RISC-V Watchpoint Commands
+
+*/
+//-----------------------------------------------------------------------------
+
+package riscv
+
+import (
+	"fmt"
+	"strings"
+
+	cli "github.com/deadsy/go-cli"
+	"github.com/deadsy/rvdbg/cpu/riscv/rv"
+)
+
+//-----------------------------------------------------------------------------
+
+// socSymbol returns the address and size of a "PERIPHERAL" or "PERIPHERAL.REGISTER" name.
+func socSymbol(c *cli.CLI, name string) (uint, uint, bool) {
+	dev, drv := c.User.(target).GetSoC()
+	x := strings.Split(name, ".")
+	p, err := dev.GetPeripheral(x[0])
+	if err != nil {
+		return 0, 0, false
+	}
+	if len(x) == 1 {
+		return p.Addr, p.Size, true
+	}
+	if len(x) != 2 {
+		return 0, 0, false
+	}
+	r, err := p.GetRegister(x[1])
+	if err != nil {
+		return 0, 0, false
+	}
+	size := r.Size
+	if size == 0 {
+		size = drv.GetRegisterSize(r)
+	}
+	return p.Addr + r.Offset, size >> 3, true
+}
+
+// watchArg converts watchpoint arguments to an (address, size, name) tuple.
+func watchArg(c *cli.CLI, dbg rv.Debug, args []string) (uint, uint, string, error) {
+	err := cli.CheckArgc(args, []int{1, 2})
+	if err != nil {
+		return 0, 0, "", err
+	}
+	var addr, size uint
+	var name string
+	if a, n, ok := socSymbol(c, args[0]); ok {
+		addr, size, name = a, n, args[0]
+	} else if r := c.User.(target).GetSymbols().Region(args[0]); r != nil {
+		addr, size, name = r.Addr, r.Size, args[0]
+	} else {
+		maxAddr := uint((1 << dbg.GetAddressSize()) - 1)
+		addr, err = cli.UintArg(args[0], [2]uint{0, maxAddr}, 16)
+		if err != nil {
+			return 0, 0, "", err
+		}
+		size = 1
+	}
+	if len(args) == 2 {
+		size, err = cli.UintArg(args[1], [2]uint{1, 0x100000000}, 16)
+		if err != nil {
+			return 0, 0, "", err
+		}
+	}
+	return addr, size, name, nil
+}
+
+// watchAdd adds a watchpoint.
+func watchAdd(c *cli.CLI, args []string, typ rv.WatchType) {
+	dbg := c.User.(target).GetRiscvDebug()
+	addr, size, name, err := watchArg(c, dbg, args)
+	if err != nil {
+		c.User.Put(fmt.Sprintf("%s\n", err))
+		return
+	}
+	var wp *rv.Watchpoint
+	err = haltedDo(dbg, func() error {
+		var err error
+		wp, err = rv.AddWatchpoint(dbg, typ, addr, size, name)
+		return err
+	})
+	if err != nil {
+		c.User.Put(fmt.Sprintf("unable to add watchpoint: %v\n", err))
+		return
+	}
+	c.User.Put(fmt.Sprintf("watchpoint %d: %s\n", wp.ID, wp))
+}
+
+// watchHelp is help for the watchpoint add commands.
+var watchHelp = []cli.Help{
+	{"<addr/name> [size]", "watch a memory region"},
+	{"  addr", "address (hex)"},
+	{"  name", "peripheral, peripheral.register (USART0.DATA) or symbol name"},
+	{"  size", "size in bytes (hex), default is 1 or the register/symbol size"},
+}
+
+var cmdWatchRead = cli.Leaf{
+	Descr: "add a read watchpoint",
+	F: func(c *cli.CLI, args []string) {
+		watchAdd(c, args, rv.WatchRead)
+	},
+}
+
+var cmdWatchWrite = cli.Leaf{
+	Descr: "add a write watchpoint",
+	F: func(c *cli.CLI, args []string) {
+		watchAdd(c, args, rv.WatchWrite)
+	},
+}
+
+var cmdWatchReadWrite = cli.Leaf{
+	Descr: "add a read/write watchpoint",
+	F: func(c *cli.CLI, args []string) {
+		watchAdd(c, args, rv.WatchReadWrite)
+	},
+}
+
+var cmdWatchExecute = cli.Leaf{
+	Descr: "add an execute watchpoint",
+	F: func(c *cli.CLI, args []string) {
+		watchAdd(c, args, rv.WatchExecute)
+	},
+}
+
+var cmdWatchDelete = cli.Leaf{
+	Descr: "delete a watchpoint",
+	F: func(c *cli.CLI, args []string) {
+		dbg := c.User.(target).GetRiscvDebug()
+		id, err := idArg(args)
+		if err != nil {
+			c.User.Put(fmt.Sprintf("%s\n", err))
+			return
+		}
+		err = haltedDo(dbg, func() error {
+			return rv.RemoveWatchpoint(dbg, id)
+		})
+		if err != nil {
+			c.User.Put(fmt.Sprintf("unable to delete watchpoint: %v\n", err))
+		}
+	},
+}
+
+var cmdWatchList = cli.Leaf{
+	Descr: "list the watchpoints",
+	F: func(c *cli.CLI, args []string) {
+		dbg := c.User.(target).GetRiscvDebug()
+		c.User.Put(fmt.Sprintf("%s\n", rv.WatchpointsString(dbg)))
+	},
+}
+
+// watchIDHelp is help for commands taking a watchpoint identifier.
+var watchIDHelp = []cli.Help{
+	{"<id>", "watchpoint identifier"},
+}
+
+// WatchMenu is the watchpoint submenu.
+var WatchMenu = cli.Menu{
+	{"delete", cmdWatchDelete, watchIDHelp},
+	{"list", cmdWatchList},
+	{"r", cmdWatchRead, watchHelp},
+	{"rw", cmdWatchReadWrite, watchHelp},
+	{"w", cmdWatchWrite, watchHelp},
+	{"x", cmdWatchExecute, watchHelp},
+}
+
+//-----------------------------------------------------------------------------
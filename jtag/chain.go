@@ -157,6 +157,36 @@ func NewChain(drv Driver, info ChainInfo) (*Chain, error) {
 	return ch, nil
 }
 
+// Discover returns the chain information for an unknown JTAG chain.
+// The IR length of each device can't be determined from a scan, so only single device chains are supported.
+func Discover(drv Driver) (ChainInfo, error) {
+	ch := &Chain{
+		drv: drv,
+	}
+	err := ch.drv.TapReset()
+	if err != nil {
+		return nil, err
+	}
+	ch.n, err = ch.numDevices()
+	if err != nil {
+		return nil, err
+	}
+	if ch.n != 1 {
+		return nil, fmt.Errorf("jtag chain: found %d devices, can only discover single device chains", ch.n)
+	}
+	irlen, err := ch.irLength()
+	if err != nil {
+		return nil, err
+	}
+	code, err := ch.readIDCodes()
+	if err != nil {
+		return nil, err
+	}
+	return ChainInfo{
+		{irlen, IDCode(code[0]), "unknown"},
+	}, nil
+}
+
 func (ch *Chain) String() string {
 	s := []string{}
 	s = append(s, fmt.Sprintf("chain: irlen %d devices %d", ch.irlen, len(ch.dev)))
@@ -11,6 +11,7 @@ package jtag
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/deadsy/rvdbg/bitstr"
 )
@@ -103,6 +104,11 @@ func (dev *Device) testIRCapture() (bool, error) {
 	return val&3 == 1, nil
 }
 
+// SystemReset pulses the system reset line of the JTAG driver.
+func (dev *Device) SystemReset(delay time.Duration) error {
+	return dev.drv.SystemReset(delay)
+}
+
 // GetIRLength returns the IR length for the device.
 func (dev *Device) GetIRLength() int {
 	return dev.irlen
@@ -20,7 +20,8 @@ import (
 //-----------------------------------------------------------------------------
 
 var helpMemRegion = []cli.Help{
-	{"<addr/name> [len]", "memory region"},
+	{"[-v] <addr/name> [len]", "memory region"},
+	{"  -v", "virtual address, translated with the page tables"},
 	{"  addr", "address (hex), default is 0"},
 	{"  name", "region name (string), see \"map\" command"},
 	{"  len", "length (hex), defaults to region size or 0x100"},
@@ -30,7 +31,11 @@ var helpMemRegion = []cli.Help{
 // memory display
 
 func display(c *cli.CLI, args []string, width uint) {
-	drv := c.User.(target).GetMemoryDriver()
+	drv, args, err := virtualArg(c.User.(target).GetMemoryDriver(), args)
+	if err != nil {
+		c.User.Put(fmt.Sprintf("%s\n", err))
+		return
+	}
 	r, err := RegionArg(drv, args)
 	if err != nil {
 		c.User.Put(fmt.Sprintf("%s\n", err))
@@ -163,7 +168,8 @@ var cmdWrite64 = cli.Leaf{
 // memory to file
 
 var helpMemToFile = []cli.Help{
-	{"<filename> <addr/name> [len]", "read from memory, write to file"},
+	{"[-v] <filename> <addr/name> [len]", "read from memory, write to file"},
+	{"  -v", "virtual address, translated with the page tables"},
 	{"  filename", "filename (string)"},
 	{"  addr", "address (hex), default is 0"},
 	{"  name", "region name (string), see \"map\" command"},
@@ -173,10 +179,14 @@ var helpMemToFile = []cli.Help{
 var cmdToFile = cli.Leaf{
 	Descr: "read from memory, write to file",
 	F: func(c *cli.CLI, args []string) {
-		drv := c.User.(target).GetMemoryDriver()
+		drv, args, err := virtualArg(c.User.(target).GetMemoryDriver(), args)
+		if err != nil {
+			c.User.Put(fmt.Sprintf("%s\n", err))
+			return
+		}
 
 		// process the arguments
-		err := cli.CheckArgc(args, []int{2, 3})
+		err = cli.CheckArgc(args, []int{2, 3})
 		if err != nil {
 			c.User.Put(fmt.Sprintf("%s\n", err))
 			return
@@ -230,7 +240,11 @@ var cmdToFile = cli.Leaf{
 var cmdPic = cli.Leaf{
 	Descr: "display a pictorial summary of memory",
 	F: func(c *cli.CLI, args []string) {
-		drv := c.User.(target).GetMemoryDriver()
+		drv, args, err := virtualArg(c.User.(target).GetMemoryDriver(), args)
+		if err != nil {
+			c.User.Put(fmt.Sprintf("%s\n", err))
+			return
+		}
 
 		// get the arguments
 		region, err := RegionArg(drv, args)
@@ -271,7 +285,11 @@ var cmdPic = cli.Leaf{
 var cmdCheckSum = cli.Leaf{
 	Descr: "calcuate md5 checksum of memory region",
 	F: func(c *cli.CLI, args []string) {
-		drv := c.User.(target).GetMemoryDriver()
+		drv, args, err := virtualArg(c.User.(target).GetMemoryDriver(), args)
+		if err != nil {
+			c.User.Put(fmt.Sprintf("%s\n", err))
+			return
+		}
 
 		// get the arguments
 		region, err := RegionArg(drv, args)
@@ -333,7 +351,11 @@ func cmpBuf(a, b []uint) bool {
 }
 
 func cmdTest(c *cli.CLI, args []string, width uint) {
-	drv := c.User.(target).GetMemoryDriver()
+	drv, args, err := virtualArg(c.User.(target).GetMemoryDriver(), args)
+	if err != nil {
+		c.User.Put(fmt.Sprintf("%s\n", err))
+		return
+	}
 	// get the arguments
 	region, err := RegionArg(drv, args)
 	if err != nil {
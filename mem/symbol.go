@@ -0,0 +1,142 @@
+//-----------------------------------------------------------------------------
+/*
+
+Symbol Table
+
+Function, object and untyped (assembly label) symbols are read from the
+symbol table of an ELF file. They are used as memory region arguments and to name code addresses (func+0x1c).
+
+*/
+//-----------------------------------------------------------------------------
+
+package mem
+
+import (
+	"debug/elf"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+//-----------------------------------------------------------------------------
+
+// Symbol is a program symbol.
+type Symbol struct {
+	Name string // symbol name
+	Addr uint   // address
+	Size uint   // size in bytes (0 == unknown)
+}
+
+// Region returns the memory region for a symbol.
+// Symbols without a size (assembly labels) are given a 32-bit word.
+func (s *Symbol) Region() *Region {
+	size := s.Size
+	if size == 0 {
+		size = 4
+	}
+	return NewRegion(s.Name, s.Addr, size, nil)
+}
+
+// SymbolTable is a set of program symbols.
+type SymbolTable struct {
+	Filename string             // source file
+	byName   map[string]*Symbol // symbols by name
+	byAddr   []*Symbol          // symbols sorted by address
+}
+
+// ReadSymbols reads the function, object and untyped symbols from an ELF file.
+func ReadSymbols(filename string) (*SymbolTable, error) {
+	f, err := elf.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	syms, err := f.Symbols()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+	t := &SymbolTable{
+		Filename: filename,
+		byName:   make(map[string]*Symbol),
+	}
+	for _, s := range syms {
+		if s.Name == "" || s.Section == elf.SHN_UNDEF {
+			continue
+		}
+		switch elf.ST_TYPE(s.Info) {
+		case elf.STT_FUNC, elf.STT_OBJECT:
+		case elf.STT_NOTYPE:
+			if !labelSymbol(f, s) {
+				continue
+			}
+		default:
+			continue
+		}
+		x := &Symbol{
+			Name: s.Name,
+			Addr: uint(s.Value),
+			Size: uint(s.Size),
+		}
+		// a global symbol takes the name from a local symbol
+		if _, ok := t.byName[x.Name]; !ok || elf.ST_BIND(s.Info) == elf.STB_GLOBAL {
+			t.byName[x.Name] = x
+		}
+		t.byAddr = append(t.byAddr, x)
+	}
+	sort.SliceStable(t.byAddr, func(i, j int) bool {
+		return t.byAddr[i].Addr < t.byAddr[j].Addr
+	})
+	return t, nil
+}
+
+// labelSymbol returns true if an untyped symbol is a label in an allocated section.
+// Absolute symbols, mapping symbols ($x, $d) and local labels (.L) are excluded.
+func labelSymbol(f *elf.File, s elf.Symbol) bool {
+	if s.Section >= elf.SHN_LORESERVE || int(s.Section) >= len(f.Sections) {
+		return false
+	}
+	if f.Sections[s.Section].Flags&elf.SHF_ALLOC == 0 {
+		return false
+	}
+	return !strings.HasPrefix(s.Name, "$") && !strings.HasPrefix(s.Name, ".L")
+}
+
+// Len returns the number of symbols.
+func (t *SymbolTable) Len() int {
+	return len(t.byAddr)
+}
+
+// Lookup returns a symbol by name (or nil).
+func (t *SymbolTable) Lookup(name string) *Symbol {
+	return t.byName[name]
+}
+
+// Find returns the symbol containing an address (or nil).
+func (t *SymbolTable) Find(addr uint) *Symbol {
+	// the first symbol above the address
+	i := sort.Search(len(t.byAddr), func(i int) bool {
+		return t.byAddr[i].Addr > addr
+	})
+	for i--; i >= 0; i-- {
+		s := t.byAddr[i]
+		if addr == s.Addr || addr < s.Addr+s.Size {
+			return s
+		}
+	}
+	return nil
+}
+
+// AddrString returns the "symbol+offset" string for an address.
+// An empty string is returned if no symbol contains the address.
+func (t *SymbolTable) AddrString(addr uint) string {
+	s := t.Find(addr)
+	if s == nil {
+		return ""
+	}
+	if addr == s.Addr {
+		return s.Name
+	}
+	return fmt.Sprintf("%s+0x%x", s.Name, addr-s.Addr)
+}
+
+//-----------------------------------------------------------------------------
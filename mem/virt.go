@@ -0,0 +1,99 @@
+//-----------------------------------------------------------------------------
+/*
+
+Virtual Memory Access
+
+Memory drivers that can translate virtual addresses implement the Translator
+interface. The virtual memory driver wraps a memory driver and translates
+each page of an access before passing it to the underlying driver.
+
+*/
+//-----------------------------------------------------------------------------
+
+package mem
+
+import (
+	"errors"
+
+	"github.com/deadsy/rvdbg/util"
+)
+
+//-----------------------------------------------------------------------------
+
+// Translator is the virtual address translation api.
+type Translator interface {
+	Translate(va uint) (uint, error) // translate a virtual address to a physical address
+}
+
+// pageSize is the translation granularity.
+const pageSize = 4096
+
+//-----------------------------------------------------------------------------
+
+// virtDriver is a memory driver for virtual addresses.
+type virtDriver struct {
+	Driver
+	t Translator
+}
+
+// pageSplit returns the number of width-bit values to access on the page of addr.
+func pageSplit(width, addr, n uint) uint {
+	shift := util.WidthToShift(width)
+	k := (pageSize - (addr & (pageSize - 1))) >> shift
+	return min(k, n)
+}
+
+// RdMem reads n x width-bit values from virtual memory.
+func (v *virtDriver) RdMem(width, addr, n uint) ([]uint, error) {
+	val := make([]uint, 0, n)
+	for n > 0 {
+		k := pageSplit(width, addr, n)
+		pa, err := v.t.Translate(addr)
+		if err != nil {
+			return nil, err
+		}
+		x, err := v.Driver.RdMem(width, pa, k)
+		if err != nil {
+			return nil, err
+		}
+		val = append(val, x...)
+		addr += k * (width >> 3)
+		n -= k
+	}
+	return val, nil
+}
+
+// WrMem writes n x width-bit values to virtual memory.
+func (v *virtDriver) WrMem(width, addr uint, val []uint) error {
+	for len(val) > 0 {
+		k := pageSplit(width, addr, uint(len(val)))
+		pa, err := v.t.Translate(addr)
+		if err != nil {
+			return err
+		}
+		err = v.Driver.WrMem(width, pa, val[:k])
+		if err != nil {
+			return err
+		}
+		addr += k * (width >> 3)
+		val = val[k:]
+	}
+	return nil
+}
+
+//-----------------------------------------------------------------------------
+
+// virtualArg processes a leading "-v" argument.
+// It returns the memory driver to use and the remaining arguments.
+func virtualArg(drv Driver, args []string) (Driver, []string, error) {
+	if len(args) == 0 || args[0] != "-v" {
+		return drv, args, nil
+	}
+	t, ok := drv.(Translator)
+	if !ok {
+		return nil, nil, errors.New("virtual addresses are not supported")
+	}
+	return &virtDriver{drv, t}, args[1:], nil
+}
+
+//-----------------------------------------------------------------------------
@@ -57,13 +57,18 @@ func (r *Register) GetField(name string) *Field {
 	}
 	for i := range r.Fields {
 		f := &r.Fields[i]
-		if r.Name == name {
+		if f.Name == name {
 			return f
 		}
 	}
 	return nil
 }
 
+// Removed returns true if the register has been removed from the peripheral.
+func (r *Register) Removed() bool {
+	return r.ignore
+}
+
 //-----------------------------------------------------------------------------
 
 // regSize returns the size of the register in bits.
@@ -45,24 +45,39 @@ var Info = target.Info{
 
 // menuRoot is the root menu.
 var menuRoot = cli.Menu{
+	{"bp", riscv.BpMenu, "breakpoint functions"},
+	{"continue", riscv.CmdContinue},
 	{"cpu", riscv.Menu, "cpu functions"},
 	{"csr", riscv.CmdCSR, riscv.CsrHelp},
 	{"da", riscv.CmdDisassemble, riscv.DisassembleHelp},
 	{"dbg", rv13.Menu, "debugger functions"},
 	{"exit", target.CmdExit},
+	{"finish", riscv.CmdFinish},
 	{"flash", flash.Menu, "flash functions"},
+	{"gdb", riscv.CmdGdb, riscv.GdbHelp},
 	{"gpio", gpio.Menu, "gpio functions"},
-	{"gpr", riscv.CmdGpr},
-	{"halt", riscv.CmdHalt},
+	{"gpr", riscv.CmdGpr, riscv.GprHelp},
+	{"halt", riscv.CmdHalt, riscv.HaltHelp},
 	{"hart", riscv.CmdHart, riscv.HartHelp},
 	{"help", target.CmdHelp},
 	{"history", target.CmdHistory, cli.HistoryHelp},
 	{"i2c", i2c.Menu, "i2c functions"},
+	{"irq", riscv.CmdIrq},
 	{"jtag", jtag.Menu, "jtag functions"},
+	{"load", riscv.CmdLoad, riscv.LoadHelp},
 	{"map", soc.CmdMap},
 	{"mem", mem.Menu, "memory functions"},
+	{"next", riscv.CmdNext},
+	{"pc", riscv.CmdPC, riscv.PCHelp},
+	{"pmp", riscv.CmdPMP},
 	{"regs", soc.CmdRegs, soc.RegsHelp},
-	{"resume", riscv.CmdResume},
+	{"reset", riscv.CmdReset, riscv.ResetHelp},
+	{"resume", riscv.CmdResume, riscv.ResumeHelp},
+	{"semihost", riscv.CmdSemihost, riscv.SemihostHelp},
+	{"step", riscv.CmdStep, riscv.StepHelp},
+	{"stepi", riscv.CmdStepi, riscv.StepHelp},
+	{"symbols", riscv.CmdSymbols, riscv.SymbolsHelp},
+	{"watch", riscv.WatchMenu, "watchpoint functions"},
 }
 
 //-----------------------------------------------------------------------------
@@ -172,13 +187,16 @@ type Target struct {
 	socDevice   *soc.Device
 	socDriver   *socDriver
 	memDriver   *memDriver
-	csrDriver   *csrDriver
+	csrDriver   *riscv.CsrDriver
+	symbols     *riscv.Symbols
 	gpioDriver  *gd32vf103.GpioDriver
 	flashDriver *gd32vf103.FlashDriver
+	poller      *riscv.Poller
 }
 
 // New returns a new gd32v target.
-func New(jtagDriver jtag.Driver) (target.Target, error) {
+// The authenticator is used for a locked debug module (nil == no authentication).
+func New(jtagDriver jtag.Driver, auth rv13.Authenticator) (target.Target, error) {
 
 	// get the JTAG state
 	state, err := jtagDriver.GetState()
@@ -210,7 +228,7 @@ func New(jtagDriver jtag.Driver) (target.Target, error) {
 		return nil, err
 	}
 
-	rvDebug, err := riscv.NewDebug(jtagDevice)
+	rvDebug, err := riscv.NewDebug(jtagDevice, auth)
 	if err != nil {
 		return nil, err
 	}
@@ -231,32 +249,41 @@ func New(jtagDriver jtag.Driver) (target.Target, error) {
 		return nil, err
 	}
 
-	return &Target{
+	symbols := riscv.NewSymbols()
+	t := &Target{
 		jtagDevice:  jtagDevice,
 		rvDebug:     rvDebug,
 		socDevice:   socDevice,
 		socDriver:   socDriver,
-		memDriver:   newMemDriver(rvDebug, socDevice),
-		csrDriver:   newCsrDriver(rvDebug),
+		memDriver:   newMemDriver(rvDebug, socDevice, symbols),
+		csrDriver:   riscv.NewCsrDriver(rvDebug),
+		symbols:     symbols,
 		gpioDriver:  gpioDriver,
 		flashDriver: flashDriver,
-	}, nil
+	}
+
+	// start the halt poller
+	t.poller = riscv.NewPoller(rvDebug, t, func() string { return rvDebug.GetPrompt(Info.Name) })
+	t.poller.Start()
+
+	return t, nil
 }
 
 //-----------------------------------------------------------------------------
 
 // GetPrompt returns the target prompt string.
 func (t *Target) GetPrompt() string {
-	return t.rvDebug.GetPrompt(Info.Name)
+	return t.poller.GetPrompt()
 }
 
 // GetMenuRoot returns the target root menu.
 func (t *Target) GetMenuRoot() []cli.MenuItem {
-	return menuRoot
+	return t.poller.Menu(menuRoot)
 }
 
 // Shutdown shuts down the target application.
 func (t *Target) Shutdown() {
+	t.poller.Stop()
 }
 
 // Put outputs a string to the user application.
@@ -291,6 +318,11 @@ func (t *Target) GetSoC() (*soc.Device, soc.Driver) {
 	return t.socDevice, t.socDriver
 }
 
+// GetSymbols returns the program symbol table.
+func (t *Target) GetSymbols() *riscv.Symbols {
+	return t.symbols
+}
+
 // GetCSR returns the CSR device and driver.
 func (t *Target) GetCSR() (*soc.Device, soc.Driver) {
 	return t.rvDebug.GetCurrentHart().CSR, t.csrDriver
@@ -0,0 +1,234 @@
+//-----------------------------------------------------------------------------
+/*
+
+Generic RISC-V Target
+
+For boards without a handwritten SoC description.
+The JTAG chain is discovered and the memory map is built from the
+configuration string/dtb pointed to by the debug module.
+
+*/
+//-----------------------------------------------------------------------------
+
+package generic
+
+import (
+	"errors"
+	"os"
+
+	cli "github.com/deadsy/go-cli"
+	"github.com/deadsy/rvdbg/cpu/riscv"
+	"github.com/deadsy/rvdbg/cpu/riscv/config"
+	"github.com/deadsy/rvdbg/cpu/riscv/rv"
+	"github.com/deadsy/rvdbg/cpu/riscv/rv11"
+	"github.com/deadsy/rvdbg/cpu/riscv/rv13"
+	"github.com/deadsy/rvdbg/itf"
+	"github.com/deadsy/rvdbg/jtag"
+	"github.com/deadsy/rvdbg/mem"
+	"github.com/deadsy/rvdbg/soc"
+	"github.com/deadsy/rvdbg/target"
+	"github.com/deadsy/rvdbg/util/log"
+)
+
+//-----------------------------------------------------------------------------
+
+// Info is target information.
+var Info = target.Info{
+	Name:     "generic",
+	Descr:    "Generic RISC-V (memory map from the configuration string/dtb)",
+	DbgType:  itf.TypeNone,
+	DbgMode:  itf.ModeJtag,
+	DbgSpeed: 1000,
+}
+
+//-----------------------------------------------------------------------------
+
+// menuRoot is the root menu.
+var menuRoot = cli.Menu{
+	{"bp", riscv.BpMenu, "breakpoint functions"},
+	{"config", riscv.CmdConfig},
+	{"continue", riscv.CmdContinue},
+	{"cpu", riscv.Menu, "cpu functions"},
+	{"csr", riscv.CmdCSR, riscv.CsrHelp},
+	{"da", riscv.CmdDisassemble, riscv.DisassembleHelp},
+	{"dbg", rv13.Menu, "debugger functions"},
+	{"exit", target.CmdExit},
+	{"finish", riscv.CmdFinish},
+	{"fpr", riscv.CmdFpr, riscv.FprHelp},
+	{"gdb", riscv.CmdGdb, riscv.GdbHelp},
+	{"gpr", riscv.CmdGpr, riscv.GprHelp},
+	{"halt", riscv.CmdHalt, riscv.HaltHelp},
+	{"hart", riscv.CmdHart, riscv.HartHelp},
+	{"help", target.CmdHelp},
+	{"history", target.CmdHistory, cli.HistoryHelp},
+	{"irq", riscv.CmdIrq},
+	{"jtag", jtag.Menu, "jtag functions"},
+	{"load", riscv.CmdLoad, riscv.LoadHelp},
+	{"map", soc.CmdMap},
+	{"mem", mem.Menu, "memory functions"},
+	{"next", riscv.CmdNext},
+	{"pc", riscv.CmdPC, riscv.PCHelp},
+	{"pmp", riscv.CmdPMP},
+	{"regs", soc.CmdRegs, soc.RegsHelp},
+	{"reset", riscv.CmdReset, riscv.ResetHelp},
+	{"resume", riscv.CmdResume, riscv.ResumeHelp},
+	{"semihost", riscv.CmdSemihost, riscv.SemihostHelp},
+	{"step", riscv.CmdStep, riscv.StepHelp},
+	{"stepi", riscv.CmdStepi, riscv.StepHelp},
+	{"symbols", riscv.CmdSymbols, riscv.SymbolsHelp},
+	{"vreg", riscv.CmdVreg, riscv.VregHelp},
+	{"vtop", riscv.CmdVtop, riscv.VtopHelp},
+	{"watch", riscv.WatchMenu, "watchpoint functions"},
+}
+
+// getMenuRoot returns the root menu with the debugger functions for the debug module version.
+func getMenuRoot(dbg rv.Debug) cli.Menu {
+	m := append(cli.Menu{}, menuRoot...)
+	if _, ok := dbg.(*rv11.Debug); ok {
+		for i := range m {
+			if m[i][0] == "dbg" {
+				m[i] = cli.MenuItem{"dbg", rv11.Menu, "debugger functions"}
+			}
+		}
+	}
+	return m
+}
+
+//-----------------------------------------------------------------------------
+
+// Target is the application structure for the target.
+type Target struct {
+	jtagDevice *jtag.Device
+	rvDebug    rv.Debug
+	socDevice  *soc.Device
+	memDriver  *memDriver
+	csrDriver  *riscv.CsrDriver
+	symbols    *riscv.Symbols
+	socDriver  *socDriver
+	poller     *riscv.Poller
+}
+
+// New returns a new generic target.
+// The authenticator is used for a locked debug module (nil == no authentication).
+func New(jtagDriver jtag.Driver, auth rv13.Authenticator) (target.Target, error) {
+
+	// get the JTAG state
+	state, err := jtagDriver.GetState()
+	if err != nil {
+		return nil, err
+	}
+
+	// check the ~SRST state
+	if !state.Srst {
+		return nil, errors.New("target ~SRST line asserted, target is held in reset")
+	}
+
+	// discover the jtag chain
+	chainInfo, err := jtag.Discover(jtagDriver)
+	if err != nil {
+		return nil, err
+	}
+
+	// make the jtag chain
+	jtagChain, err := jtag.NewChain(jtagDriver, chainInfo)
+	if err != nil {
+		return nil, err
+	}
+
+	// make the jtag device for the cpu core
+	jtagDevice, err := jtagChain.GetDevice(0)
+	if err != nil {
+		return nil, err
+	}
+
+	// create the CPU debug interface
+	rvDebug, err := riscv.NewDebug(jtagDevice, auth)
+	if err != nil {
+		return nil, err
+	}
+
+	// create the SoC device from the platform configuration
+	var socDevice *soc.Device
+	cfg, err := config.Read(rvDebug)
+	if err != nil {
+		log.Info.Printf("no platform configuration: %v", err)
+		socDevice = &soc.Device{Name: Info.Name}
+	} else {
+		socDevice = cfg.NewSoC()
+	}
+	socDevice.Setup()
+
+	symbols := riscv.NewSymbols()
+	t := &Target{
+		jtagDevice: jtagDevice,
+		rvDebug:    rvDebug,
+		socDevice:  socDevice,
+		memDriver:  newMemDriver(rvDebug, socDevice, symbols),
+		socDriver:  newSocDriver(rvDebug),
+		csrDriver:  riscv.NewCsrDriver(rvDebug),
+		symbols:    symbols,
+	}
+
+	// start the halt poller
+	t.poller = riscv.NewPoller(rvDebug, t, func() string { return rvDebug.GetPrompt(Info.Name) })
+	t.poller.Start()
+
+	return t, nil
+
+}
+
+//-----------------------------------------------------------------------------
+
+// GetPrompt returns the target prompt string.
+func (t *Target) GetPrompt() string {
+	return t.poller.GetPrompt()
+}
+
+// GetMenuRoot returns the target root menu.
+func (t *Target) GetMenuRoot() []cli.MenuItem {
+	return t.poller.Menu(getMenuRoot(t.rvDebug))
+}
+
+// Shutdown shuts down the target application.
+func (t *Target) Shutdown() {
+	t.poller.Stop()
+}
+
+// Put outputs a string to the user application.
+func (t *Target) Put(s string) {
+	os.Stdout.WriteString(s)
+}
+
+//-----------------------------------------------------------------------------
+
+// GetMemoryDriver returns a memory driver for this target.
+func (t *Target) GetMemoryDriver() mem.Driver {
+	return t.memDriver
+}
+
+// GetRiscvDebug returns a RISC-V debug driver for this target.
+func (t *Target) GetRiscvDebug() rv.Debug {
+	return t.rvDebug
+}
+
+// GetSoC returns the SoC device and driver.
+func (t *Target) GetSoC() (*soc.Device, soc.Driver) {
+	return t.socDevice, t.socDriver
+}
+
+// GetSymbols returns the program symbol table.
+func (t *Target) GetSymbols() *riscv.Symbols {
+	return t.symbols
+}
+
+// GetCSR returns the CSR device and driver.
+func (t *Target) GetCSR() (*soc.Device, soc.Driver) {
+	return t.rvDebug.GetCurrentHart().CSR, t.csrDriver
+}
+
+// GetJtagDevice returns the JTAG device.
+func (t *Target) GetJtagDevice() *jtag.Device {
+	return t.jtagDevice
+}
+
+//-----------------------------------------------------------------------------
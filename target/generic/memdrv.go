@@ -0,0 +1,83 @@
+//-----------------------------------------------------------------------------
+/*
+
+Memory Driver
+
+This code implements the mem.Driver interface.
+
+*/
+//-----------------------------------------------------------------------------
+
+package generic
+
+import (
+	"fmt"
+
+	"github.com/deadsy/rvdbg/cpu/riscv"
+	"github.com/deadsy/rvdbg/cpu/riscv/rv"
+	"github.com/deadsy/rvdbg/mem"
+	"github.com/deadsy/rvdbg/soc"
+)
+
+//-----------------------------------------------------------------------------
+
+type memDriver struct {
+	dbg     rv.Debug
+	dev     *soc.Device
+	symbols *riscv.Symbols
+}
+
+func newMemDriver(dbg rv.Debug, dev *soc.Device, symbols *riscv.Symbols) *memDriver {
+	return &memDriver{
+		dbg:     dbg,
+		dev:     dev,
+		symbols: symbols,
+	}
+}
+
+// GetAddressSize returns the address size in bits.
+func (m *memDriver) GetAddressSize() uint {
+	return m.dbg.GetAddressSize()
+}
+
+// GetDefaultRegion returns a default memory region.
+func (m *memDriver) GetDefaultRegion() *mem.Region {
+	return mem.NewRegion("", 0, 0x100, nil)
+}
+
+// LookupSymbol returns an address and size for a symbol.
+func (m *memDriver) LookupSymbol(name string) *mem.Region {
+	if r := m.symbols.Region(name); r != nil {
+		r.SetAddrSize(m.GetAddressSize())
+		return r
+	}
+	p, err := m.dev.GetPeripheral(name)
+	if err != nil {
+		return nil
+	}
+	return mem.NewRegion(name, p.Addr, p.Size, nil)
+}
+
+// RdMem reads n x width-bit values from memory.
+func (m *memDriver) RdMem(width, addr, n uint) ([]uint, error) {
+	return m.dbg.RdMem(width, addr, n)
+}
+
+// WrMem wirtes n x width-bit values to memory.
+func (m *memDriver) WrMem(width, addr uint, val []uint) error {
+	return m.dbg.WrMem(width, addr, val)
+}
+
+// Translate translates a virtual address to a physical address.
+func (m *memDriver) Translate(va uint) (uint, error) {
+	t, err := rv.Translate(m.dbg, va)
+	if err != nil {
+		return 0, err
+	}
+	if t.Fault != "" {
+		return 0, fmt.Errorf("page fault at 0x%x: %s", va, t.Fault)
+	}
+	return t.PA, nil
+}
+
+//-----------------------------------------------------------------------------
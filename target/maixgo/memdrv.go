@@ -11,6 +11,9 @@ This code implements the mem.Driver interface.
 package maixgo
 
 import (
+	"fmt"
+
+	"github.com/deadsy/rvdbg/cpu/riscv"
 	"github.com/deadsy/rvdbg/cpu/riscv/rv"
 	"github.com/deadsy/rvdbg/mem"
 	"github.com/deadsy/rvdbg/soc"
@@ -19,14 +22,16 @@ import (
 //-----------------------------------------------------------------------------
 
 type memDriver struct {
-	dbg rv.Debug
-	dev *soc.Device
+	dbg     rv.Debug
+	dev     *soc.Device
+	symbols *riscv.Symbols
 }
 
-func newMemDriver(dbg rv.Debug, dev *soc.Device) *memDriver {
+func newMemDriver(dbg rv.Debug, dev *soc.Device, symbols *riscv.Symbols) *memDriver {
 	return &memDriver{
-		dbg: dbg,
-		dev: dev,
+		dbg:     dbg,
+		dev:     dev,
+		symbols: symbols,
 	}
 }
 
@@ -42,6 +47,10 @@ func (m *memDriver) GetDefaultRegion() *mem.Region {
 
 // LookupSymbol returns an address and size for a symbol.
 func (m *memDriver) LookupSymbol(name string) *mem.Region {
+	if r := m.symbols.Region(name); r != nil {
+		r.SetAddrSize(m.GetAddressSize())
+		return r
+	}
 	p, err := m.dev.GetPeripheral(name)
 	if err != nil {
 		return nil
@@ -59,4 +68,16 @@ func (m *memDriver) WrMem(width, addr uint, val []uint) error {
 	return m.dbg.WrMem(width, addr, val)
 }
 
+// Translate translates a virtual address to a physical address.
+func (m *memDriver) Translate(va uint) (uint, error) {
+	t, err := rv.Translate(m.dbg, va)
+	if err != nil {
+		return 0, err
+	}
+	if t.Fault != "" {
+		return 0, fmt.Errorf("page fault at 0x%x: %s", va, t.Fault)
+	}
+	return t.PA, nil
+}
+
 //-----------------------------------------------------------------------------
@@ -11,6 +11,7 @@ This code implements the mem.Driver interface.
 package redv
 
 import (
+	"github.com/deadsy/rvdbg/cpu/riscv"
 	"github.com/deadsy/rvdbg/cpu/riscv/rv"
 	"github.com/deadsy/rvdbg/mem"
 	"github.com/deadsy/rvdbg/soc"
@@ -19,14 +20,16 @@ import (
 //-----------------------------------------------------------------------------
 
 type memDriver struct {
-	dbg rv.Debug
-	dev *soc.Device
+	dbg     rv.Debug
+	dev     *soc.Device
+	symbols *riscv.Symbols
 }
 
-func newMemDriver(dbg rv.Debug, dev *soc.Device) *memDriver {
+func newMemDriver(dbg rv.Debug, dev *soc.Device, symbols *riscv.Symbols) *memDriver {
 	return &memDriver{
-		dbg: dbg,
-		dev: dev,
+		dbg:     dbg,
+		dev:     dev,
+		symbols: symbols,
 	}
 }
 
@@ -42,6 +45,10 @@ func (m *memDriver) GetDefaultRegion() *mem.Region {
 
 // LookupSymbol returns an address and size for a symbol.
 func (m *memDriver) LookupSymbol(name string) *mem.Region {
+	if r := m.symbols.Region(name); r != nil {
+		r.SetAddrSize(m.GetAddressSize())
+		return r
+	}
 	p, err := m.dev.GetPeripheral(name)
 	if err != nil {
 		return nil
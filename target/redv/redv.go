@@ -43,21 +43,36 @@ var Info = target.Info{
 
 // menuRoot is the root menu.
 var menuRoot = cli.Menu{
+	{"bp", riscv.BpMenu, "breakpoint functions"},
+	{"continue", riscv.CmdContinue},
 	{"cpu", riscv.Menu, "cpu functions"},
 	{"csr", riscv.CmdCSR, riscv.CsrHelp},
 	{"da", riscv.CmdDisassemble, riscv.DisassembleHelp},
 	{"dbg", rv13.Menu, "debugger functions"},
 	{"exit", target.CmdExit},
-	{"gpr", riscv.CmdGpr},
-	{"halt", riscv.CmdHalt},
+	{"finish", riscv.CmdFinish},
+	{"gdb", riscv.CmdGdb, riscv.GdbHelp},
+	{"gpr", riscv.CmdGpr, riscv.GprHelp},
+	{"halt", riscv.CmdHalt, riscv.HaltHelp},
 	{"hart", riscv.CmdHart, riscv.HartHelp},
 	{"help", target.CmdHelp},
 	{"history", target.CmdHistory, cli.HistoryHelp},
+	{"irq", riscv.CmdIrq},
 	{"jtag", jtag.Menu, "jtag functions"},
+	{"load", riscv.CmdLoad, riscv.LoadHelp},
 	{"map", soc.CmdMap},
 	{"mem", mem.Menu, "memory functions"},
+	{"next", riscv.CmdNext},
+	{"pc", riscv.CmdPC, riscv.PCHelp},
+	{"pmp", riscv.CmdPMP},
 	{"regs", soc.CmdRegs, soc.RegsHelp},
-	{"resume", riscv.CmdResume},
+	{"reset", riscv.CmdReset, riscv.ResetHelp},
+	{"resume", riscv.CmdResume, riscv.ResumeHelp},
+	{"semihost", riscv.CmdSemihost, riscv.SemihostHelp},
+	{"step", riscv.CmdStep, riscv.StepHelp},
+	{"stepi", riscv.CmdStepi, riscv.StepHelp},
+	{"symbols", riscv.CmdSymbols, riscv.SymbolsHelp},
+	{"watch", riscv.WatchMenu, "watchpoint functions"},
 }
 
 //-----------------------------------------------------------------------------
@@ -68,12 +83,15 @@ type Target struct {
 	rvDebug    rv.Debug
 	socDevice  *soc.Device
 	memDriver  *memDriver
-	csrDriver  *csrDriver
+	csrDriver  *riscv.CsrDriver
+	symbols    *riscv.Symbols
 	socDriver  *socDriver
+	poller     *riscv.Poller
 }
 
 // New returns a new redv target.
-func New(jtagDriver jtag.Driver) (target.Target, error) {
+// The authenticator is used for a locked debug module (nil == no authentication).
+func New(jtagDriver jtag.Driver, auth rv13.Authenticator) (target.Target, error) {
 
 	// get the JTAG state
 	state, err := jtagDriver.GetState()
@@ -104,7 +122,7 @@ func New(jtagDriver jtag.Driver) (target.Target, error) {
 	}
 
 	// create the CPU debug interface
-	rvDebug, err := riscv.NewDebug(jtagDevice)
+	rvDebug, err := riscv.NewDebug(jtagDevice, auth)
 	if err != nil {
 		return nil, err
 	}
@@ -112,14 +130,22 @@ func New(jtagDriver jtag.Driver) (target.Target, error) {
 	// create the SoC device
 	socDevice := fe310.NewSoC(fe310.G002).Setup()
 
-	return &Target{
+	symbols := riscv.NewSymbols()
+	t := &Target{
 		jtagDevice: jtagDevice,
 		rvDebug:    rvDebug,
 		socDevice:  socDevice,
-		memDriver:  newMemDriver(rvDebug, socDevice),
+		memDriver:  newMemDriver(rvDebug, socDevice, symbols),
 		socDriver:  newSocDriver(rvDebug),
-		csrDriver:  newCsrDriver(rvDebug),
-	}, nil
+		csrDriver:  riscv.NewCsrDriver(rvDebug),
+		symbols:    symbols,
+	}
+
+	// start the halt poller
+	t.poller = riscv.NewPoller(rvDebug, t, func() string { return rvDebug.GetPrompt(Info.Name) })
+	t.poller.Start()
+
+	return t, nil
 
 }
 
@@ -127,16 +153,17 @@ func New(jtagDriver jtag.Driver) (target.Target, error) {
 
 // GetPrompt returns the target prompt string.
 func (t *Target) GetPrompt() string {
-	return t.rvDebug.GetPrompt(Info.Name)
+	return t.poller.GetPrompt()
 }
 
 // GetMenuRoot returns the target root menu.
 func (t *Target) GetMenuRoot() []cli.MenuItem {
-	return menuRoot
+	return t.poller.Menu(menuRoot)
 }
 
 // Shutdown shuts down the target application.
 func (t *Target) Shutdown() {
+	t.poller.Stop()
 }
 
 // Put outputs a string to the user application.
@@ -161,6 +188,11 @@ func (t *Target) GetSoC() (*soc.Device, soc.Driver) {
 	return t.socDevice, t.socDriver
 }
 
+// GetSymbols returns the program symbol table.
+func (t *Target) GetSymbols() *riscv.Symbols {
+	return t.symbols
+}
+
 // GetCSR returns the CSR device and driver.
 func (t *Target) GetCSR() (*soc.Device, soc.Driver) {
 	return t.rvDebug.GetCurrentHart().CSR, t.csrDriver